@@ -1,59 +1,168 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/mgrabazey/tic-tac-toe/internal/api/transport/http"
 	"github.com/mgrabazey/tic-tac-toe/internal/app/module/game"
+	"github.com/mgrabazey/tic-tac-toe/internal/app/module/idempotency"
+	domainrepo "github.com/mgrabazey/tic-tac-toe/internal/domain/repo"
 	"github.com/mgrabazey/tic-tac-toe/internal/pkg/postgres"
 	"github.com/mgrabazey/tic-tac-toe/internal/service/migration"
 	"github.com/mgrabazey/tic-tac-toe/internal/service/repo"
+	"github.com/mgrabazey/tic-tac-toe/internal/service/repo/file"
+	"github.com/mgrabazey/tic-tac-toe/internal/service/repo/memory"
 )
 
+const (
+	storageMemory   = "memory"
+	storageFile     = "file"
+	storagePostgres = "postgres"
+)
+
+// errUsage is returned by run if the flags are invalid.
+var errUsage = errors.New("invalid flags")
+
 func main() {
+	err := run()
+	if err == errUsage {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalf("unable to run service: %v\n", err)
+	}
+}
+
+// run runs the service until it is interrupted. The deferred calls are made before it
+// returns, so that the storage is closed on any error.
+func run() error {
 	var (
 		publicUrl  string
+		storage    string
+		dataDir    string
 		dbUser     string
 		dbPassword string
 		dbHost     string
 		dbPort     string
 		dbName     string
+
+		abandonAfter   time.Duration
+		sweepInterval  time.Duration
+		idempotencyTtl time.Duration
 	)
 	flag.StringVar(&publicUrl, "public-url", os.Getenv("PUBLIC_URL"), "Public URL of the API")
+	flag.StringVar(&storage, "storage", os.Getenv("STORAGE"), "Storage of games, either memory, file or postgres. Defaults to file if the data directory is set and to postgres otherwise")
+	flag.StringVar(&dataDir, "data-dir", os.Getenv("DATA_DIR"), "Directory of the file storage")
 	flag.StringVar(&dbUser, "db-user", os.Getenv("DB_USER"), "Database user")
 	flag.StringVar(&dbPassword, "db-password", os.Getenv("DB_PASSWORD"), "Database password")
 	flag.StringVar(&dbHost, "db-host", os.Getenv("DB_HOST"), "Database host")
 	flag.StringVar(&dbPort, "db-port", os.Getenv("DB_PORT"), "Database port")
 	flag.StringVar(&dbName, "db-name", os.Getenv("DB_NAME"), "Database name")
+	flag.DurationVar(&abandonAfter, "abandon-after", durationEnv("ABANDON_AFTER", 24*time.Hour), "Period without moves after which running games are abandoned, 0 disables abandoning")
+	flag.DurationVar(&sweepInterval, "sweep-interval", durationEnv("SWEEP_INTERVAL", time.Minute), "Interval between checks for abandoned games")
+	flag.DurationVar(&idempotencyTtl, "idempotency-ttl", durationEnv("IDEMPOTENCY_TTL", 24*time.Hour), "Period the responses of requests made with Idempotency-Key are replayed for")
 	flag.Parse()
 
-	if publicUrl == "" || dbUser == "" || dbPassword == "" || dbHost == "" || dbPort == "" || dbName == "" {
-		flag.PrintDefaults()
-		os.Exit(1)
+	if publicUrl == "" || abandonAfter < 0 || sweepInterval <= 0 || idempotencyTtl <= 0 {
+		return errUsage
+	}
+	if storage == "" {
+		storage = storagePostgres
+		if dataDir != "" {
+			storage = storageFile
+		}
 	}
 
-	db, err := postgres.Conn(&postgres.Config{
-		User:     dbUser,
-		Password: dbPassword,
-		Host:     dbHost,
-		Port:     dbPort,
-		Name:     dbName,
-	})
-	if err != nil {
-		log.Fatalf("unable to connect to database: %v\n", err)
+	var (
+		gameRepo        domainrepo.GameRepository
+		idempotencyRepo domainrepo.IdempotencyRepository
+	)
+	switch storage {
+	case storageMemory:
+		log.Printf("Keep games in memory, they are lost on exit.\n")
+		gameRepo = memory.NewGameRepository()
+		idempotencyRepo = memory.NewIdempotencyRepository()
+	case storageFile:
+		if dataDir == "" {
+			return errUsage
+		}
+
+		r, err := file.NewGameRepository(dataDir)
+		if err != nil {
+			return fmt.Errorf("unable to open data directory: %w", err)
+		}
+		defer r.Close()
+
+		log.Printf("Keep games in %s, idempotency keys are kept in memory.\n", dataDir)
+		gameRepo = r
+		idempotencyRepo = memory.NewIdempotencyRepository()
+	case storagePostgres:
+		if dbUser == "" || dbPassword == "" || dbHost == "" || dbPort == "" || dbName == "" {
+			return errUsage
+		}
+
+		db, err := postgres.Conn(&postgres.Config{
+			User:     dbUser,
+			Password: dbPassword,
+			Host:     dbHost,
+			Port:     dbPort,
+			Name:     dbName,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to connect to database: %w", err)
+		}
+
+		// Run database migrations.
+		err = migration.Run(db)
+		if err != nil {
+			return fmt.Errorf("unable to run migrations: %w", err)
+		}
+
+		gameRepo = repo.NewGameRepository(db)
+		idempotencyRepo = repo.NewIdempotencyRepository(db)
+	default:
+		return errUsage
 	}
 
-	// Run database migrations.
-	err = migration.Run(db)
-	if err != nil {
-		log.Fatalf("unable to run service: %v\n", err)
+	// Stop on interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := game.NewService(gameRepo, game.NewDefaultRegistry(game.DefaultRand))
+
+	// Run abandoned games sweeper.
+	var wg sync.WaitGroup
+	if abandonAfter > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			game.NewSweeper(s, abandonAfter, sweepInterval).Run(ctx)
+		}()
 	}
 
 	// Tun HTTP application
-	err = httpx.Run(publicUrl, game.NewService(repo.NewGameRepository(db)))
+	err := httpx.Run(ctx, publicUrl, s, idempotency.NewService(idempotencyRepo, idempotencyTtl))
+	stop()
+	wg.Wait()
+	return err
+}
+
+// durationEnv returns the duration of the environment variable or def if the variable
+// is not set or invalid.
+func durationEnv(key string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
 	if err != nil {
-		log.Fatalf("unable to run service: %v\n", err)
+		return def
 	}
+	return v
 }
@@ -3,9 +3,10 @@ module github.com/mgrabazey/tic-tac-toe
 go 1.20
 
 require (
-	github.com/felixge/httpsnoop v1.0.1 // indirect
-	github.com/google/uuid v1.3.0 // indirect
-	github.com/gorilla/handlers v1.5.1 // indirect
-	github.com/gorilla/mux v1.8.0 // indirect
-	github.com/lib/pq v1.10.7 // indirect
+	github.com/google/uuid v1.3.0
+	github.com/gorilla/handlers v1.5.1
+	github.com/gorilla/mux v1.8.0
+	github.com/lib/pq v1.10.7
 )
+
+require github.com/felixge/httpsnoop v1.0.1 // indirect
@@ -0,0 +1,175 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
+
+// Notation is a JSON representation of a board.
+type Notation string
+
+const (
+	// NotationString is the plain string, e.g. "XO--X--OX".
+	NotationString Notation = "string"
+	// NotationRows is the string of rows separated by "/", e.g. "XO-/-X-/-OX".
+	NotationRows Notation = "rows"
+	// NotationGrid is the 2D array of cells, e.g. [["X","0","-"],["-","X","-"],["-","0","X"]].
+	NotationGrid Notation = "grid"
+	// NotationCells is the object of occupied cell indices by char, e.g.
+	// {"size":3,"X":[0,4,8],"0":[1,7]}.
+	NotationCells Notation = "cells"
+)
+
+// NotationFromString converts the notation name. An empty name means NotationString.
+func NotationFromString(s string) (Notation, error) {
+	switch n := Notation(s); n {
+	case "":
+		return NotationString, nil
+	case NotationString, NotationRows, NotationGrid, NotationCells:
+		return n, nil
+	}
+	return "", fmt.Errorf("unknown notation: %s", s)
+}
+
+// Board is a board accepted in any Notation and encoded in the selected one.
+type Board struct {
+	s string
+	n Notation
+}
+
+func NewBoard(board domain.GameBoard, notation Notation) *Board {
+	return &Board{
+		s: board.String(),
+		n: notation,
+	}
+}
+
+// String returns the board in the notation accepted by domain.GameBoardFromString.
+func (b *Board) String() string {
+	if b == nil {
+		return ""
+	}
+	return b.s
+}
+
+func (b *Board) MarshalJSON() ([]byte, error) {
+	size := 0
+	for size*size < len(b.s) {
+		size++
+	}
+	switch b.n {
+	case NotationRows:
+		r := make([]string, size)
+		for n := range r {
+			r[n] = b.s[n*size : (n+1)*size]
+		}
+		return json.Marshal(strings.Join(r, "/"))
+	case NotationGrid:
+		r := make([][]string, size)
+		for n := range r {
+			r[n] = strings.Split(b.s[n*size:(n+1)*size], "")
+		}
+		return json.Marshal(r)
+	case NotationCells:
+		m := map[string]any{
+			"size": size,
+		}
+		for _, c := range []domain.GameBoardChar{domain.GameBoardCharCross, domain.GameBoardCharNought} {
+			s := []int{}
+			for n := range b.s {
+				if domain.GameBoardChar(b.s[n]) == c {
+					s = append(s, n)
+				}
+			}
+			m[string(c)] = s
+		}
+		return json.Marshal(m)
+	default:
+		return json.Marshal(b.s)
+	}
+}
+
+func (b *Board) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return fmt.Errorf("invalid board")
+	}
+	switch data[0] {
+	case '"':
+		b.n = NotationString
+		return json.Unmarshal(data, &b.s)
+	case '[':
+		// Accept the rows either as arrays of cells or as strings.
+		var r []json.RawMessage
+		err := json.Unmarshal(data, &r)
+		if err != nil {
+			return err
+		}
+		var s strings.Builder
+		for _, i := range r {
+			var v string
+			if err = json.Unmarshal(i, &v); err != nil {
+				var c []string
+				if err = json.Unmarshal(i, &c); err != nil {
+					return err
+				}
+				v = strings.Join(c, "")
+			}
+			if len(v) != len(r) {
+				return fmt.Errorf("invalid board: rows must have %d cells", len(r))
+			}
+			s.WriteString(v)
+		}
+		b.n, b.s = NotationGrid, s.String()
+		return nil
+	case '{':
+		var m map[string]json.RawMessage
+		err := json.Unmarshal(data, &m)
+		if err != nil {
+			return err
+		}
+		size := domain.GameBoardDefaultSize
+		if v, ok := m["size"]; ok {
+			if err = json.Unmarshal(v, &size); err != nil {
+				return err
+			}
+			delete(m, "size")
+		}
+		if size < domain.GameBoardMinSize || size > domain.GameBoardMaxSize {
+			return fmt.Errorf("invalid board size: %d", size)
+		}
+		c := bytes.Repeat([]byte(domain.GameBoardCharNone), size*size)
+		k := make([]string, 0, len(m))
+		for i := range m {
+			k = append(k, i)
+		}
+		sort.Strings(k)
+		for _, i := range k {
+			ch, err := domain.GameBoardCharFromString(i)
+			if err != nil {
+				return fmt.Errorf("invalid board char: %s", i)
+			}
+			var s []int
+			if err = json.Unmarshal(m[i], &s); err != nil {
+				return err
+			}
+			for _, n := range s {
+				if n < 0 || n >= len(c) {
+					return fmt.Errorf("invalid board cell: %d", n)
+				}
+				if domain.GameBoardChar(c[n]) != domain.GameBoardCharNone {
+					return fmt.Errorf("board cell is occupied twice: %d", n)
+				}
+				c[n] = ch[0]
+			}
+		}
+		b.n, b.s = NotationCells, string(c)
+		return nil
+	}
+	return fmt.Errorf("invalid board")
+}
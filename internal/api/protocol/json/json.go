@@ -1,28 +1,255 @@
 package jsonx
 
-import "github.com/mgrabazey/tic-tac-toe/internal/domain"
+import (
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
 
 type Games []*Game
 
-func NewGames(games domain.Games) Games {
+func NewGames(games domain.Games, notation Notation) Games {
 	s := make(Games, len(games))
 	for n, i := range games {
-		s[n] = NewGame(i)
+		s[n] = NewGame(i, notation)
+	}
+	return s
+}
+
+// NewGameTree creates the tree of games. Forks are nested in the games they have been
+// forked from, the games not forked from any of the games are at the top.
+func NewGameTree(games domain.Games, notation Notation) Games {
+	m := make(map[domain.GameId]*Game, len(games))
+	for _, i := range games {
+		m[i.Id] = NewGame(i, notation)
+	}
+	s := Games{}
+	for _, i := range games {
+		if i.Parent != nil {
+			if p, ok := m[i.Parent.Id]; ok {
+				p.Forks = append(p.Forks, m[i.Id])
+				continue
+			}
+		}
+		s = append(s, m[i.Id])
 	}
 	return s
 }
 
 type Game struct {
-	Id     string `json:"id"`
-	Board  string `json:"board"`
-	Status string `json:"status"`
+	Id         string `json:"id"`
+	Board      *Board `json:"board"`
+	Start      *Board `json:"start,omitempty"`
+	Size       int    `json:"size"`
+	Win        int    `json:"win"`
+	Status     string `json:"status"`
+	Difficulty string `json:"difficulty"`
+	PlayerChar string `json:"playerChar"`
+	FirstMover string `json:"firstMover"`
+	ToMove     string `json:"toMove,omitempty"`
+	LastMover  string `json:"lastMover,omitempty"`
+	// WinningMove is the index of the cell of the move completed the winning line.
+	WinningMove *int         `json:"winningMove,omitempty"`
+	WinningLine *WinningLine `json:"winningLine,omitempty"`
+	Winner      string       `json:"winner,omitempty"`
+	Hints       int          `json:"hints"`
+	// UndoLimit is the number of times the player can take back a move.
+	UndoLimit *int `json:"undoLimit"`
+	Undos     int  `json:"undos"`
+	// Parent is the game this game has been forked from.
+	Parent *GameParent `json:"parent,omitempty"`
+	// Forks are the games forked from this game, they are only set in the tree of games.
+	Forks Games `json:"forks,omitempty"`
 }
 
-func NewGame(game *domain.Game) *Game {
+func NewGame(game *domain.Game, notation Notation) *Game {
 	return &Game{
-		Id:     string(game.Id),
-		Board:  game.Board.String(),
-		Status: string(game.Status),
+		Id:          string(game.Id),
+		Board:       NewBoard(game.Board, notation),
+		Start:       NewBoard(game.Start, notation),
+		Size:        game.Board.Size(),
+		Win:         game.Board.Win(),
+		Status:      string(game.Status),
+		Difficulty:  string(game.Strategy),
+		PlayerChar:  string(game.PlayerChar()),
+		FirstMover:  NewPlayer(game.FirstMover),
+		ToMove:      NewPlayer(game.ToMove()),
+		LastMover:   NewPlayer(game.LastMover),
+		WinningMove: game.WinningMove,
+		WinningLine: NewWinningLine(game.WinningLine),
+		Winner:      string(game.Winner),
+		Hints:       game.Hints,
+		UndoLimit:   &game.UndoLimit,
+		Undos:       game.Undos,
+		Parent:      NewGameParent(game.Parent),
+	}
+}
+
+type GameParent struct {
+	Id  string `json:"id"`
+	Ply int    `json:"ply"`
+}
+
+func NewGameParent(parent *domain.GameParent) *GameParent {
+	if parent == nil {
+		return nil
+	}
+	return &GameParent{
+		Id:  string(parent.Id),
+		Ply: parent.Ply,
+	}
+}
+
+type WinningLine struct {
+	Kind  string `json:"kind"`
+	Cells []int  `json:"cells"`
+}
+
+func NewWinningLine(line *domain.GameBoardLine) *WinningLine {
+	if line == nil {
+		return nil
+	}
+	return &WinningLine{
+		Kind:  string(line.Kind),
+		Cells: line.Cells,
+	}
+}
+
+const (
+	PlayerHuman    = "player"
+	PlayerComputer = "computer"
+)
+
+func NewPlayer(player domain.GamePlayer) string {
+	switch player {
+	case domain.GamePlayerHuman:
+		return PlayerHuman
+	case domain.GamePlayerComputer:
+		return PlayerComputer
+	}
+	return ""
+}
+
+type GameMoves []*GameMove
+
+func NewGameMoves(moves domain.GameMoves) GameMoves {
+	s := make(GameMoves, len(moves))
+	for n, i := range moves {
+		s[n] = NewGameMove(i)
+	}
+	return s
+}
+
+type GameMove struct {
+	Ply    int       `json:"ply"`
+	Player string    `json:"player"`
+	Cell   int       `json:"cell"`
+	Char   string    `json:"char"`
+	Time   time.Time `json:"time"`
+}
+
+func NewGameMove(move *domain.GameMove) *GameMove {
+	return &GameMove{
+		Ply:    move.Ply,
+		Player: string(move.Player),
+		Cell:   move.Cell,
+		Char:   string(move.Char),
+		Time:   move.Time,
+	}
+}
+
+type Move struct {
+	Cell *int `json:"cell,omitempty"`
+	Row  *int `json:"row,omitempty"`
+	Col  *int `json:"col,omitempty"`
+}
+
+type MoveResult struct {
+	Game  *Game `json:"game"`
+	Reply *int  `json:"reply"`
+	Move  int   `json:"move"`
+}
+
+func NewMoveResult(game *domain.Game, reply *int, move int, notation Notation) *MoveResult {
+	return &MoveResult{
+		Game:  NewGame(game, notation),
+		Reply: reply,
+		Move:  move,
+	}
+}
+
+type DrawOffer struct {
+	Accepted bool  `json:"accepted"`
+	Game     *Game `json:"game"`
+}
+
+func NewDrawOffer(game *domain.Game, accepted bool, notation Notation) *DrawOffer {
+	return &DrawOffer{
+		Accepted: accepted,
+		Game:     NewGame(game, notation),
+	}
+}
+
+type Hint struct {
+	Cell    int    `json:"cell"`
+	Row     int    `json:"row"`
+	Col     int    `json:"col"`
+	Outcome string `json:"outcome"`
+	Plies   int    `json:"plies"`
+	Hints   int    `json:"hints"`
+}
+
+func NewHint(game *domain.Game, cell int, outcome string, plies int) *Hint {
+	i, j := game.Board.Cell(cell)
+	return &Hint{
+		Cell:    cell,
+		Row:     i,
+		Col:     j,
+		Outcome: outcome,
+		Plies:   plies,
+		Hints:   game.Hints,
+	}
+}
+
+type AnalysisRequest struct {
+	Board  *Board `json:"board"`
+	Win    int    `json:"win"`
+	ToMove string `json:"toMove"`
+}
+
+type Analysis struct {
+	Board  *Board            `json:"board"`
+	Size   int               `json:"size"`
+	Win    int               `json:"win"`
+	ToMove string            `json:"toMove"`
+	Moves  []*MoveEvaluation `json:"moves"`
+}
+
+func NewAnalysis(board domain.GameBoard, toMove domain.GameBoardChar, moves []*MoveEvaluation, notation Notation) *Analysis {
+	return &Analysis{
+		Board:  NewBoard(board, notation),
+		Size:   board.Size(),
+		Win:    board.Win(),
+		ToMove: string(toMove),
+		Moves:  moves,
+	}
+}
+
+type MoveEvaluation struct {
+	Cell    int    `json:"cell"`
+	Row     int    `json:"row"`
+	Col     int    `json:"col"`
+	Outcome string `json:"outcome"`
+	Plies   int    `json:"plies"`
+}
+
+func NewMoveEvaluation(board domain.GameBoard, row, col int, outcome string, plies int) *MoveEvaluation {
+	return &MoveEvaluation{
+		Cell:    board.Index(row, col),
+		Row:     row,
+		Col:     col,
+		Outcome: outcome,
+		Plies:   plies,
 	}
 }
 
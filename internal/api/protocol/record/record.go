@@ -0,0 +1,430 @@
+// Package record implements the portable game record format. A record is a text similar
+// to PGN for chess, it starts with the header tags followed by the numbered move list:
+//
+//	[Id "9b2c7b8e-6e0c-4d4e-9d6f-3f0c8b5b3f1e"]
+//	[Created "2026-10-18T10:00:00Z"]
+//	[X "Human"]
+//	[0 "Computer"]
+//	[FirstMover "Human"]
+//	[Strategy "perfect"]
+//	[Size "3"]
+//	[Win "3"]
+//	[Start "---------"]
+//	[UndoLimit "3"]
+//	[Result "X_WON"]
+//
+//	1. Xb2 {2026-10-18T10:00:01Z} 0a1 {2026-10-18T10:00:01Z}
+//	2. Xc1 {2026-10-18T10:00:05Z} 0a3 {2026-10-18T10:00:05Z}
+//	3. Xa2 {2026-10-18T10:00:09Z} 0c3 {2026-10-18T10:00:09Z}
+//	4. Xc2 {2026-10-18T10:00:12Z}
+//	X_WON
+//
+// A move is the char followed by the cell coordinates, the column letter starting with
+// "a" and the row number starting with 1 from the top. The optional comment following
+// a move holds the time it was made. Records are separated by blank lines.
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
+
+const (
+	PlayerHuman    = "Human"
+	PlayerComputer = "Computer"
+)
+
+const (
+	tagId         = "Id"
+	tagCreated    = "Created"
+	tagCross      = "X"
+	tagNought     = "0"
+	tagFirstMover = "FirstMover"
+	tagStrategy   = "Strategy"
+	tagSize       = "Size"
+	tagWin        = "Win"
+	tagStart      = "Start"
+	tagUndoLimit  = "UndoLimit"
+	tagResult     = "Result"
+)
+
+// Record is a game along with its moves.
+type Record struct {
+	Game  *domain.Game
+	Moves domain.GameMoves
+	// UndoLimit is the number of times the user can take back a move, nil unless the
+	// record has the tag.
+	UndoLimit *int
+}
+
+// Write writes the record of the game with the moves to w.
+func Write(w io.Writer, game *domain.Game, moves domain.GameMoves) error {
+	b := &strings.Builder{}
+	tag := func(name, value string) {
+		fmt.Fprintf(b, "[%s %s]\n", name, strconv.Quote(value))
+	}
+	tag(tagId, string(game.Id))
+	tag(tagCreated, game.Created.UTC().Format(time.RFC3339))
+	if game.Char == domain.GameBoardCharCross {
+		tag(tagCross, PlayerComputer)
+		tag(tagNought, PlayerHuman)
+	} else {
+		tag(tagCross, PlayerHuman)
+		tag(tagNought, PlayerComputer)
+	}
+	tag(tagFirstMover, newPlayer(game.FirstMover))
+	tag(tagStrategy, string(game.Strategy))
+	tag(tagSize, strconv.Itoa(game.Start.Size()))
+	tag(tagWin, strconv.Itoa(game.Start.Win()))
+	tag(tagStart, game.Start.String())
+	tag(tagUndoLimit, strconv.Itoa(game.UndoLimit))
+	tag(tagResult, string(game.Status))
+
+	b.WriteString("\n")
+	for n, i := range moves {
+		if n%2 == 0 {
+			if n > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(b, "%d.", n/2+1)
+		}
+		r, c := game.Board.Cell(i.Cell)
+		fmt.Fprintf(b, " %s%c%d {%s}", i.Char, 'a'+c, r+1, i.Time.UTC().Format(time.RFC3339))
+	}
+	if len(moves) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString(string(game.Status))
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Read reads all the records from r. The games get the header tags, the missing
+// identifier and creation time are left empty. The moves are parsed but not replayed,
+// so the games don't get the board after the moves. The status is the result of the
+// record if any.
+func Read(r io.Reader) ([]*Record, error) {
+	var (
+		s    []*Record
+		p    *parser
+		line int
+	)
+	flush := func() error {
+		if p == nil {
+			return nil
+		}
+		v, err := p.record()
+		if err != nil {
+			return fmt.Errorf("record %d: %w", len(s)+1, err)
+		}
+		s = append(s, v)
+		p = nil
+		return nil
+	}
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line++
+		l := strings.TrimSpace(sc.Text())
+		if l == "" {
+			continue
+		}
+		// A tag following the move list starts the next record.
+		if strings.HasPrefix(l, "[") && p != nil && p.text.Len() > 0 {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		if p == nil {
+			p = &parser{tags: make(map[string]string)}
+		}
+		if strings.HasPrefix(l, "[") {
+			err := p.tag(l)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			continue
+		}
+		p.text.WriteString(l)
+		p.text.WriteString(" ")
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if len(s) == 0 {
+		return nil, fmt.Errorf("no records")
+	}
+	return s, nil
+}
+
+type parser struct {
+	tags map[string]string
+	text strings.Builder
+}
+
+// tag parses the tag line, e.g. [Id "9b2c7b8e-6e0c-4d4e-9d6f-3f0c8b5b3f1e"].
+func (p *parser) tag(line string) error {
+	if !strings.HasSuffix(line, "]") {
+		return fmt.Errorf("invalid tag")
+	}
+	n, v, ok := strings.Cut(strings.TrimSpace(line[1:len(line)-1]), " ")
+	if !ok {
+		return fmt.Errorf("invalid tag")
+	}
+	v, err := strconv.Unquote(strings.TrimSpace(v))
+	if err != nil {
+		return fmt.Errorf("invalid tag value: %s", n)
+	}
+	// The letter O is an alias of the nought.
+	if n == "O" {
+		n = tagNought
+	}
+	if _, ok = p.tags[n]; ok {
+		return fmt.Errorf("duplicate tag: %s", n)
+	}
+	p.tags[n] = v
+	return nil
+}
+
+// record builds the record from the tags and the move list.
+func (p *parser) record() (*Record, error) {
+	g := &domain.Game{
+		Status:   domain.GameStatus(p.tags[tagResult]),
+		Strategy: domain.GameStrategy(p.tags[tagStrategy]),
+	}
+
+	var err error
+	if v := p.tags[tagId]; v != "" {
+		g.Id, err = domain.GameIdFromString(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag value: %s", tagId)
+		}
+	}
+	if v := p.tags[tagCreated]; v != "" {
+		g.Created, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag value: %s", tagCreated)
+		}
+	}
+
+	// Detect the computer's char by the players.
+	x, ok := playerFromString(p.tags[tagCross])
+	if !ok {
+		return nil, fmt.Errorf("invalid tag value: %s", tagCross)
+	}
+	o, ok := playerFromString(p.tags[tagNought])
+	if !ok || o == x {
+		return nil, fmt.Errorf("invalid tag value: %s", tagNought)
+	}
+	players := map[domain.GameBoardChar]domain.GamePlayer{
+		domain.GameBoardCharCross:  x,
+		domain.GameBoardCharNought: o,
+	}
+	g.Char = domain.GameBoardCharNought
+	if x == domain.GamePlayerComputer {
+		g.Char = domain.GameBoardCharCross
+	}
+
+	g.Start, err = p.start()
+	if err != nil {
+		return nil, err
+	}
+
+	m, r, err := p.moves(g.Start, players)
+	if err != nil {
+		return nil, err
+	}
+	if g.Status == "" {
+		g.Status = r
+	}
+
+	// Detect the first mover by the first move unless set.
+	if v, ok := p.tags[tagFirstMover]; ok {
+		g.FirstMover, ok = playerFromString(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid tag value: %s", tagFirstMover)
+		}
+	} else if len(m) > 0 {
+		g.FirstMover = m[0].Player
+	} else {
+		g.FirstMover = domain.GamePlayerHuman
+	}
+
+	v := &Record{
+		Game:  g,
+		Moves: m,
+	}
+	if s, ok := p.tags[tagUndoLimit]; ok {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag value: %s", tagUndoLimit)
+		}
+		v.UndoLimit = &n
+	}
+	return v, nil
+}
+
+// start builds the starting position by the Start, Size and Win tags. The blank board
+// of the size is used if the Start tag is not set.
+func (p *parser) start() (domain.GameBoard, error) {
+	size := domain.GameBoardDefaultSize
+	if v, ok := p.tags[tagSize]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return domain.GameBoard{}, fmt.Errorf("invalid tag value: %s", tagSize)
+		}
+		size = n
+	}
+	win := size
+	if v, ok := p.tags[tagWin]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return domain.GameBoard{}, fmt.Errorf("invalid tag value: %s", tagWin)
+		}
+		win = n
+	}
+	v, ok := p.tags[tagStart]
+	if !ok {
+		b, err := domain.NewGameBoardOfSize(size, win)
+		if err != nil {
+			return domain.GameBoard{}, fmt.Errorf("invalid tag value: %s", tagSize)
+		}
+		return b, nil
+	}
+	b, err := domain.GameBoardFromStringWithWin(v, win)
+	if err != nil {
+		return domain.GameBoard{}, fmt.Errorf("invalid tag value: %s", tagStart)
+	}
+	if b.Size() != size {
+		return domain.GameBoard{}, fmt.Errorf("invalid tag value: %s", tagSize)
+	}
+	return b, nil
+}
+
+// moves parses the move list made on the starting position. It returns the moves and
+// the result ending the list if any.
+func (p *parser) moves(start domain.GameBoard, players map[domain.GameBoardChar]domain.GamePlayer) (domain.GameMoves, domain.GameStatus, error) {
+	var (
+		s      domain.GameMoves
+		result string
+	)
+	t := p.text.String()
+	for len(t) > 0 {
+		// Skip spaces.
+		if t[0] == ' ' {
+			t = t[1:]
+			continue
+		}
+		if result != "" {
+			return nil, "", fmt.Errorf("unexpected text after result: %s", t)
+		}
+
+		// The comment holds the time of the preceding move.
+		if t[0] == '{' {
+			n := strings.IndexByte(t, '}')
+			if n < 0 {
+				return nil, "", fmt.Errorf("unterminated comment")
+			}
+			if len(s) == 0 {
+				return nil, "", fmt.Errorf("unexpected comment")
+			}
+			v, err := time.Parse(time.RFC3339, strings.TrimSpace(t[1:n]))
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid move time: %s", t[1:n])
+			}
+			s[len(s)-1].Time = v
+			t = t[n+1:]
+			continue
+		}
+
+		w := t
+		if n := strings.IndexAny(t, " {"); n >= 0 {
+			w = t[:n]
+		}
+		t = t[len(w):]
+
+		// The move number precedes every pair of moves.
+		if strings.HasSuffix(w, ".") {
+			n, err := strconv.Atoi(w[:len(w)-1])
+			if err != nil || len(s)%2 != 0 || n != len(s)/2+1 {
+				return nil, "", fmt.Errorf("invalid move number: %s", w)
+			}
+			continue
+		}
+
+		m, err := move(w, start, players)
+		if err != nil {
+			// The move list ends with the result.
+			if !isResult(w) {
+				return nil, "", err
+			}
+			if v, ok := p.tags[tagResult]; ok && w != v {
+				return nil, "", fmt.Errorf("result doesn't match %s tag: %s", tagResult, w)
+			}
+			result = w
+			continue
+		}
+		m.Ply = start.Moves() + len(s) + 1
+		s = append(s, m)
+	}
+	return s, domain.GameStatus(result), nil
+}
+
+// isResult checks if s looks like the game status, e.g. X_WON.
+func isResult(s string) bool {
+	for _, c := range s {
+		if (c < 'A' || c > 'Z') && c != '_' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// move parses the move, e.g. Xb2.
+func move(s string, start domain.GameBoard, players map[domain.GameBoardChar]domain.GamePlayer) (*domain.GameMove, error) {
+	if len(s) < 3 {
+		return nil, fmt.Errorf("invalid move: %s", s)
+	}
+	c, err := domain.GameBoardCharFromString(s[:1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid move: %s", s)
+	}
+	j := int(s[1]) - 'a'
+	i, err := strconv.Atoi(s[2:])
+	if err != nil || !start.Contains(i-1, j) {
+		return nil, fmt.Errorf("invalid move: %s", s)
+	}
+	return &domain.GameMove{
+		Player: players[c],
+		Cell:   start.Index(i-1, j),
+		Char:   c,
+	}, nil
+}
+
+func newPlayer(player domain.GamePlayer) string {
+	if player == domain.GamePlayerComputer {
+		return PlayerComputer
+	}
+	return PlayerHuman
+}
+
+func playerFromString(s string) (domain.GamePlayer, bool) {
+	switch s {
+	case PlayerHuman:
+		return domain.GamePlayerHuman, true
+	case PlayerComputer:
+		return domain.GamePlayerComputer, true
+	}
+	return "", false
+}
@@ -0,0 +1,70 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/api/protocol/json"
+	"github.com/mgrabazey/tic-tac-toe/internal/app/module/game"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+)
+
+type analysisController struct {
+	s *game.Service
+}
+
+func newAnalysisController(service *game.Service) *analysisController {
+	return &analysisController{
+		s: service,
+	}
+}
+
+func (c *analysisController) analyze(writer http.ResponseWriter, request *http.Request) {
+	o, ok := notation(writer, request)
+	if !ok {
+		return
+	}
+	a := &jsonx.AnalysisRequest{}
+	err := json.NewDecoder(request.Body).Decode(a)
+	if err != nil {
+		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid request body"))
+		return
+	}
+	b, err := domain.GameBoardFromString(a.Board.String())
+	if err != nil {
+		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid board"))
+		return
+	}
+	if a.Win != 0 {
+		b, err = domain.GameBoardFromStringWithWin(a.Board.String(), a.Win)
+		if err != nil {
+			writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid win"))
+			return
+		}
+	}
+	t, ok := charFromString(a.ToMove)
+	if !ok {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Invalid toMove"))
+		return
+	}
+	err = b.Validate(t)
+	if err != nil {
+		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError(fmt.Sprintf("Invalid board: %v", err)))
+		return
+	}
+	v, err := c.s.Analyze(request.Context(), &game.AnalyzeRequest{
+		Board: b,
+		Char:  t,
+	})
+	if err != nil {
+		writeError(writer, err, jsonx.NewError(err.Error()))
+		return
+	}
+	m := make([]*jsonx.MoveEvaluation, len(v))
+	for n, i := range v {
+		m[n] = jsonx.NewMoveEvaluation(b, i.Row, i.Col, string(i.Outcome), i.Plies)
+	}
+	writeResponse(writer, http.StatusOK, jsonx.NewAnalysis(b, t, m, o))
+}
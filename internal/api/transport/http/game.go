@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/mgrabazey/tic-tac-toe/internal/api/protocol/json"
@@ -25,11 +26,23 @@ func newGameController(publicUrl string, service *game.Service) *gameController
 }
 
 func (c *gameController) all(writer http.ResponseWriter, request *http.Request) {
+	o, ok := notation(writer, request)
+	if !ok {
+		return
+	}
 	v, err := c.s.All(request.Context())
 	if err != nil {
 		writeError(writer, err, nil)
+		return
+	}
+	switch request.URL.Query().Get("view") {
+	case "", "list":
+		writeResponse(writer, http.StatusOK, jsonx.NewGames(v, o))
+	case "tree":
+		writeResponse(writer, http.StatusOK, jsonx.NewGameTree(v, o))
+	default:
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Invalid view"))
 	}
-	writeResponse(writer, http.StatusOK, jsonx.NewGames(v))
 }
 
 func (c *gameController) get(writer http.ResponseWriter, request *http.Request) {
@@ -37,22 +50,55 @@ func (c *gameController) get(writer http.ResponseWriter, request *http.Request)
 	if !ok {
 		return
 	}
+	o, ok := notation(writer, request)
+	if !ok {
+		return
+	}
 	v, err := c.s.Get(request.Context(), id)
 	if err != nil {
 		writeError(writer, err, nil)
 		return
 	}
-	writeResponse(writer, http.StatusOK, jsonx.NewGame(v))
+	writeETag(writer, v)
+	writeResponse(writer, http.StatusOK, jsonx.NewGame(v, o))
 }
 
 func (c *gameController) create(writer http.ResponseWriter, request *http.Request) {
-	b, ok := c.validateBody(writer, request)
+	g, b, ok := c.validateBody(writer, request)
 	if !ok {
 		return
 	}
-	v, err := c.s.Create(request.Context(), &game.CreateRequest{
-		Board: b,
-	})
+	r := &game.CreateRequest{
+		Board:     b,
+		Strategy:  domain.GameStrategy(g.Difficulty),
+		UndoLimit: g.UndoLimit,
+	}
+	if g.PlayerChar != "" {
+		r.Char, ok = charFromString(g.PlayerChar)
+		if !ok {
+			writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Invalid playerChar"))
+			return
+		}
+	}
+	if g.FirstMover != "" {
+		r.FirstMover, ok = playerFromString(g.FirstMover)
+		if !ok {
+			writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Invalid firstMover"))
+			return
+		}
+	}
+	if g.ToMove != "" {
+		if g.FirstMover != "" {
+			writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Either firstMover or toMove expected"))
+			return
+		}
+		r.ToMove, ok = playerFromString(g.ToMove)
+		if !ok {
+			writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Invalid toMove"))
+			return
+		}
+	}
+	v, err := c.s.Create(request.Context(), r)
 	if err != nil {
 		writeError(writer, err, jsonx.NewError(err.Error()))
 		return
@@ -67,19 +113,195 @@ func (c *gameController) update(writer http.ResponseWriter, request *http.Reques
 	if !ok {
 		return
 	}
-	b, ok := c.validateBody(writer, request)
+	o, ok := notation(writer, request)
 	if !ok {
 		return
 	}
+	e, ok := ifMatch(writer, request)
+	if !ok {
+		return
+	}
+	g, b, ok := c.validateBody(writer, request)
+	if !ok {
+		return
+	}
+	if g.Difficulty != "" {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Unexpected parameter: difficulty"))
+		return
+	}
+	if g.PlayerChar != "" {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Unexpected parameter: playerChar"))
+		return
+	}
+	if g.FirstMover != "" {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Unexpected parameter: firstMover"))
+		return
+	}
+	if g.ToMove != "" {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Unexpected parameter: toMove"))
+		return
+	}
+	if g.UndoLimit != nil {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Unexpected parameter: undoLimit"))
+		return
+	}
 	v, err := c.s.Update(request.Context(), &game.UpdateRequest{
-		Id:    id,
-		Board: b,
+		Id:      id,
+		Board:   b,
+		Version: e,
+	})
+	if err != nil {
+		writeIfMatchError(writer, err, e)
+		return
+	}
+	writeETag(writer, v)
+	writeResponse(writer, http.StatusOK, jsonx.NewGame(v, o))
+}
+
+func (c *gameController) moves(writer http.ResponseWriter, request *http.Request) {
+	id, ok := c.validateId(writer, request)
+	if !ok {
+		return
+	}
+	v, err := c.s.Moves(request.Context(), id)
+	if err != nil {
+		writeError(writer, err, nil)
+		return
+	}
+	writeResponse(writer, http.StatusOK, jsonx.NewGameMoves(v))
+}
+
+func (c *gameController) hint(writer http.ResponseWriter, request *http.Request) {
+	id, ok := c.validateId(writer, request)
+	if !ok {
+		return
+	}
+	v, err := c.s.Hint(request.Context(), id)
+	if err != nil {
+		writeError(writer, err, jsonx.NewError(err.Error()))
+		return
+	}
+	writeETag(writer, v.Game)
+	writeResponse(writer, http.StatusOK, jsonx.NewHint(v.Game, v.Cell, string(v.Evaluation.Outcome), v.Evaluation.Plies))
+}
+
+func (c *gameController) move(writer http.ResponseWriter, request *http.Request) {
+	id, ok := c.validateId(writer, request)
+	if !ok {
+		return
+	}
+	o, ok := notation(writer, request)
+	if !ok {
+		return
+	}
+	e, ok := ifMatch(writer, request)
+	if !ok {
+		return
+	}
+	m := &jsonx.Move{}
+	err := json.NewDecoder(request.Body).Decode(m)
+	if err != nil {
+		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid request body"))
+		return
+	}
+	r := &game.MoveRequest{
+		Id:      id,
+		Cell:    m.Cell,
+		Version: e,
+	}
+	switch {
+	case m.Cell != nil && m.Row == nil && m.Col == nil:
+		// OK
+	case m.Cell == nil && m.Row != nil && m.Col != nil:
+		r.Row, r.Col = *m.Row, *m.Col
+	default:
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Either cell or row and col expected"))
+		return
+	}
+	v, err := c.s.Move(request.Context(), r)
+	if err != nil {
+		writeIfMatchError(writer, err, e)
+		return
+	}
+	writeETag(writer, v.Game)
+	writeResponse(writer, http.StatusOK, jsonx.NewMoveResult(v.Game, v.Reply, v.Move, o))
+}
+
+func (c *gameController) resign(writer http.ResponseWriter, request *http.Request) {
+	id, ok := c.validateId(writer, request)
+	if !ok {
+		return
+	}
+	o, ok := notation(writer, request)
+	if !ok {
+		return
+	}
+	v, err := c.s.Resign(request.Context(), id)
+	if err != nil {
+		writeError(writer, err, jsonx.NewError(err.Error()))
+		return
+	}
+	writeETag(writer, v)
+	writeResponse(writer, http.StatusOK, jsonx.NewGame(v, o))
+}
+
+func (c *gameController) draw(writer http.ResponseWriter, request *http.Request) {
+	id, ok := c.validateId(writer, request)
+	if !ok {
+		return
+	}
+	o, ok := notation(writer, request)
+	if !ok {
+		return
+	}
+	v, err := c.s.OfferDraw(request.Context(), id)
+	if err != nil {
+		writeError(writer, err, jsonx.NewError(err.Error()))
+		return
+	}
+	writeETag(writer, v.Game)
+	writeResponse(writer, http.StatusOK, jsonx.NewDrawOffer(v.Game, v.Accepted, o))
+}
+
+func (c *gameController) fork(writer http.ResponseWriter, request *http.Request) {
+	id, ok := c.validateId(writer, request)
+	if !ok {
+		return
+	}
+	p, err := strconv.Atoi(request.URL.Query().Get("ply"))
+	if err != nil {
+		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid ply"))
+		return
+	}
+	v, err := c.s.Fork(request.Context(), &game.ForkRequest{
+		Id:  id,
+		Ply: p,
 	})
 	if err != nil {
 		writeError(writer, err, jsonx.NewError(err.Error()))
 		return
 	}
-	writeResponse(writer, http.StatusOK, jsonx.NewGame(v))
+	u := fmt.Sprintf("%s/api/v1/games/%s", c.u, v.Id)
+	writer.Header().Set("Location", u)
+	writeResponse(writer, http.StatusCreated, jsonx.NewGameLocation(u))
+}
+
+func (c *gameController) undo(writer http.ResponseWriter, request *http.Request) {
+	id, ok := c.validateId(writer, request)
+	if !ok {
+		return
+	}
+	o, ok := notation(writer, request)
+	if !ok {
+		return
+	}
+	v, err := c.s.Undo(request.Context(), id)
+	if err != nil {
+		writeError(writer, err, jsonx.NewError(err.Error()))
+		return
+	}
+	writeETag(writer, v)
+	writeResponse(writer, http.StatusOK, jsonx.NewGame(v, o))
 }
 
 func (c *gameController) remove(writer http.ResponseWriter, request *http.Request) {
@@ -104,25 +326,71 @@ func (c *gameController) validateId(writer http.ResponseWriter, request *http.Re
 	return id, true
 }
 
-func (c *gameController) validateBody(writer http.ResponseWriter, request *http.Request) (domain.GameBoard, bool) {
+func (c *gameController) validateBody(writer http.ResponseWriter, request *http.Request) (*jsonx.Game, domain.GameBoard, bool) {
 	g := &jsonx.Game{}
 	err := json.NewDecoder(request.Body).Decode(g)
 	if err != nil {
 		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid request body"))
-		return domain.GameBoard{}, false
+		return nil, domain.GameBoard{}, false
 	}
 	if g.Id != "" {
 		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Unexpected parameter: id"))
-		return domain.GameBoard{}, false
+		return nil, domain.GameBoard{}, false
 	}
 	if g.Status != "" {
 		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Unexpected parameter: status"))
-		return domain.GameBoard{}, false
+		return nil, domain.GameBoard{}, false
 	}
-	b, err := domain.GameBoardFromString(g.Board)
+	if g.Start != nil {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Unexpected parameter: start"))
+		return nil, domain.GameBoard{}, false
+	}
+	if g.Winner != "" {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Unexpected parameter: winner"))
+		return nil, domain.GameBoard{}, false
+	}
+	if g.Parent != nil {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Unexpected parameter: parent"))
+		return nil, domain.GameBoard{}, false
+	}
+	b, err := domain.GameBoardFromString(g.Board.String())
 	if err != nil {
 		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid board"))
-		return domain.GameBoard{}, false
+		return nil, domain.GameBoard{}, false
+	}
+	if g.Size != 0 && g.Size != b.Size() {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Invalid size"))
+		return nil, domain.GameBoard{}, false
+	}
+	if g.Win != 0 {
+		b, err = domain.GameBoardFromStringWithWin(g.Board.String(), g.Win)
+		if err != nil {
+			writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid win"))
+			return nil, domain.GameBoard{}, false
+		}
+	}
+	err = b.Validate(domain.GameBoardCharNone)
+	if err != nil {
+		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError(fmt.Sprintf("Invalid board: %v", err)))
+		return nil, domain.GameBoard{}, false
+	}
+	return g, b, true
+}
+
+// charFromString converts the player's char. The letters accepted by the board
+// notations are accepted as the aliases.
+func charFromString(s string) (domain.GameBoardChar, bool) {
+	c, err := domain.GameBoardCharFromString(s)
+	return c, err == nil
+}
+
+// playerFromString converts the side of the game.
+func playerFromString(s string) (domain.GamePlayer, bool) {
+	switch s {
+	case jsonx.PlayerHuman:
+		return domain.GamePlayerHuman, true
+	case jsonx.PlayerComputer:
+		return domain.GamePlayerComputer, true
 	}
-	return b, true
+	return "", false
 }
@@ -1,37 +1,131 @@
 package httpx
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/mgrabazey/tic-tac-toe/internal/api/protocol/json"
 	"github.com/mgrabazey/tic-tac-toe/internal/app/module/game"
+	"github.com/mgrabazey/tic-tac-toe/internal/app/module/idempotency"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
 	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
 )
 
-func Run(publicUrl string, gameService *game.Service) error {
+// Run runs the HTTP server until the context is done. The server is shut down gracefully
+// then, waiting for the requests in progress.
+func Run(ctx context.Context, publicUrl string, gameService *game.Service, idempotencyService *idempotency.Service) error {
 	r := mux.NewRouter()
+	r.Use(idempotent(idempotencyService))
 
 	g := newGameController(publicUrl, gameService)
+	a := newAnalysisController(gameService)
+	rc := newRecordController(publicUrl, gameService)
 
 	r.Methods(http.MethodGet).Path("/api/v1/games").HandlerFunc(g.all)
 	r.Methods(http.MethodGet).Path("/api/v1/games/{id}").HandlerFunc(g.get)
 	r.Methods(http.MethodPost).Path("/api/v1/games").HandlerFunc(g.create)
+	r.Methods(http.MethodPost).Path("/api/v1/games/import").HandlerFunc(rc.load)
 	r.Methods(http.MethodPut).Path("/api/v1/games/{id}").HandlerFunc(g.update)
 	r.Methods(http.MethodDelete).Path("/api/v1/games/{id}").HandlerFunc(g.remove)
+	r.Methods(http.MethodGet).Path("/api/v1/games/{id}/moves").HandlerFunc(g.moves)
+	r.Methods(http.MethodPost).Path("/api/v1/games/{id}/moves").HandlerFunc(g.move)
+	r.Methods(http.MethodGet).Path("/api/v1/games/{id}/hint").HandlerFunc(g.hint)
+	r.Methods(http.MethodPost).Path("/api/v1/games/{id}/undo").HandlerFunc(g.undo)
+	r.Methods(http.MethodPost).Path("/api/v1/games/{id}/fork").HandlerFunc(g.fork)
+	r.Methods(http.MethodPost).Path("/api/v1/games/{id}/resign").HandlerFunc(g.resign)
+	r.Methods(http.MethodPost).Path("/api/v1/games/{id}/draw").HandlerFunc(g.draw)
+	r.Methods(http.MethodGet).Path("/api/v1/games/{id}/record").HandlerFunc(rc.export)
+	r.Methods(http.MethodPost).Path("/api/v1/analysis").HandlerFunc(a.analyze)
 
 	s := &http.Server{
 		Handler: handlers.CORS(
 			handlers.AllowedOrigins([]string{"*"}),
 			handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "OPTIONS", "DELETE"}),
-			handlers.AllowedHeaders([]string{"Content-Type"}),
+			handlers.AllowedHeaders([]string{"Content-Type", "If-Match", "Idempotency-Key"}),
+			handlers.ExposedHeaders([]string{"ETag", "Location", "Idempotent-Replayed"}),
 		)(r),
 		Addr: ":80",
 	}
+	go func() {
+		<-ctx.Done()
+		log.Printf("Shut down HTTP server...\n")
+		err := s.Shutdown(context.Background())
+		if err != nil {
+			log.Printf("unable to shut down HTTP server: %v\n", err)
+		}
+	}()
 	log.Printf("Run HTTP server...\n")
-	return s.ListenAndServe()
+	err := s.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// notation provides the board notation of the response requested either by the notation
+// query parameter or by the profile parameter of the Accept header, e.g.
+// "application/json; profile=grid". The query parameter takes precedence.
+func notation(writer http.ResponseWriter, request *http.Request) (jsonx.Notation, bool) {
+	s := request.URL.Query().Get("notation")
+	if s == "" {
+		for _, i := range strings.Split(request.Header.Get("Accept"), ",") {
+			_, p, err := mime.ParseMediaType(strings.TrimSpace(i))
+			if err == nil && p["profile"] != "" {
+				s = p["profile"]
+				break
+			}
+		}
+	}
+	n, err := jsonx.NotationFromString(s)
+	if err != nil {
+		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid notation"))
+		return "", false
+	}
+	return n, true
+}
+
+// writeETag sets the ETag header to the game's version.
+func writeETag(writer http.ResponseWriter, game *domain.Game) {
+	writer.Header().Set("ETag", strconv.Quote(strconv.Itoa(game.Version)))
+}
+
+// ifMatch provides the game version of the If-Match header. It is nil if the header
+// is not set or "*". Only a single entity tag is supported. The precondition fails
+// for a weak or foreign tag, If-Match never matches them.
+func ifMatch(writer http.ResponseWriter, request *http.Request) (*int, bool) {
+	s := strings.TrimSpace(request.Header.Get("If-Match"))
+	if s == "" || s == "*" {
+		return nil, true
+	}
+	t := strings.TrimPrefix(s, "W/")
+	v, err := strconv.Unquote(t)
+	if err != nil || !strings.HasPrefix(t, `"`) {
+		writeError(writer, errorx.NewBadRequest(), jsonx.NewError("Invalid If-Match"))
+		return nil, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || t != s {
+		writeResponse(writer, http.StatusPreconditionFailed, jsonx.NewError("If-Match doesn't match the game"))
+		return nil, false
+	}
+	return &n, true
+}
+
+// writeIfMatchError writes the error, the conflict is reported as the failed
+// precondition if the If-Match header is set.
+func writeIfMatchError(writer http.ResponseWriter, err error, version *int) {
+	if version != nil && errorx.IsConflict(err) {
+		writeResponse(writer, http.StatusPreconditionFailed, jsonx.NewError(err.Error()))
+		return
+	}
+	writeError(writer, err, jsonx.NewError(err.Error()))
 }
 
 func writeResponse(writer http.ResponseWriter, code int, data any) {
@@ -59,6 +153,8 @@ func writeError(writer http.ResponseWriter, err error, data any) {
 		code = http.StatusBadRequest
 	case errorx.IsNotFound(err):
 		code = http.StatusNotFound
+	case errorx.IsConflict(err):
+		code = http.StatusConflict
 	default:
 		code = http.StatusInternalServerError
 	}
@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/api/protocol/json"
+	"github.com/mgrabazey/tic-tac-toe/internal/app/module/idempotency"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+)
+
+// idempotentHeaders are the response headers replayed along with the response.
+var idempotentHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// idempotent makes the requests having the Idempotency-Key header idempotent. The
+// response of the first request made with the key is kept and replayed for its repeats.
+// The key can't be reused with another request. The responses of server errors are
+// not kept, so that the request can be repeated.
+func idempotent(service *idempotency.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			k := request.Header.Get("Idempotency-Key")
+			if k == "" || request.Method == http.MethodGet || request.Method == http.MethodHead || request.Method == http.MethodOptions {
+				next.ServeHTTP(writer, request)
+				return
+			}
+
+			// Identify the request by the method, the URL and the body.
+			b, err := io.ReadAll(request.Body)
+			if err != nil {
+				writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid request body"))
+				return
+			}
+			request.Body = io.NopCloser(bytes.NewReader(b))
+			h := sha256.New()
+			h.Write([]byte(request.Method + " " + request.URL.RequestURI() + "\n"))
+			h.Write(b)
+
+			v, err := service.Begin(request.Context(), k, hex.EncodeToString(h.Sum(nil)))
+			if err != nil {
+				writeError(writer, err, jsonx.NewError(err.Error()))
+				return
+			}
+			if v != nil {
+				// Replay the response.
+				for n, i := range v.Header {
+					writer.Header().Set(n, i)
+				}
+				writer.Header().Set("Idempotent-Replayed", "true")
+				writer.WriteHeader(v.Status)
+				_, _ = writer.Write(v.Body)
+				return
+			}
+
+			r := &responseRecorder{
+				ResponseWriter: writer,
+				status:         http.StatusOK,
+			}
+			next.ServeHTTP(r, request)
+
+			// Keep the response even if the client has gone.
+			ctx := context.Background()
+			if r.status >= http.StatusInternalServerError {
+				_ = service.Abort(ctx, k)
+				return
+			}
+			m := &domain.IdempotentResponse{
+				Status: r.status,
+				Header: make(map[string]string),
+				Body:   r.body.Bytes(),
+			}
+			for _, i := range idempotentHeaders {
+				if s := writer.Header().Get(i); s != "" {
+					m.Header[i] = s
+				}
+			}
+			_ = service.Finish(ctx, k, m)
+		})
+	}
+}
+
+// responseRecorder writes the response and records it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
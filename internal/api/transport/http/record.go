@@ -0,0 +1,76 @@
+package httpx
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mgrabazey/tic-tac-toe/internal/api/protocol/json"
+	"github.com/mgrabazey/tic-tac-toe/internal/api/protocol/record"
+	"github.com/mgrabazey/tic-tac-toe/internal/app/module/game"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+)
+
+type recordController struct {
+	u string
+	s *game.Service
+}
+
+func newRecordController(publicUrl string, service *game.Service) *recordController {
+	return &recordController{
+		u: publicUrl,
+		s: service,
+	}
+}
+
+func (c *recordController) export(writer http.ResponseWriter, request *http.Request) {
+	id, err := domain.GameIdFromString(mux.Vars(request)["id"])
+	if err != nil {
+		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError("Invalid id"))
+		return
+	}
+	g, err := c.s.Get(request.Context(), id)
+	if err != nil {
+		writeError(writer, err, nil)
+		return
+	}
+	m, err := c.s.Moves(request.Context(), id)
+	if err != nil {
+		writeError(writer, err, nil)
+		return
+	}
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+	err = record.Write(writer, g, m)
+	if err != nil {
+		log.Printf("unable to write response data: %v\n", err)
+	}
+}
+
+func (c *recordController) load(writer http.ResponseWriter, request *http.Request) {
+	v, err := record.Read(request.Body)
+	if err != nil {
+		writeError(writer, errorx.WrapInBadRequest(err), jsonx.NewError(fmt.Sprintf("Invalid record: %v", err)))
+		return
+	}
+	r := make([]*game.ImportRequest, len(v))
+	for n, i := range v {
+		r[n] = &game.ImportRequest{
+			Game:      i.Game,
+			Moves:     i.Moves,
+			UndoLimit: i.UndoLimit,
+		}
+	}
+	g, err := c.s.Import(request.Context(), r)
+	if err != nil {
+		writeError(writer, err, jsonx.NewError(err.Error()))
+		return
+	}
+	l := make([]*jsonx.GameLocation, len(g))
+	for n, i := range g {
+		l[n] = jsonx.NewGameLocation(fmt.Sprintf("%s/api/v1/games/%s", c.u, i.Id))
+	}
+	writeResponse(writer, http.StatusCreated, l)
+}
@@ -18,97 +18,342 @@ func NewEngine(strategy Strategy) *Engine {
 	}
 }
 
-func (e *Engine) Start(board domain.GameBoard) (domain.GameBoardChar, domain.GameBoard, error) {
-	// Create a new blank board.
-	b := domain.NewGameBoard()
+// Start starts the game on the board. The user plays with the player char and the first
+// mover makes the first move, both are detected from the board if not set. The game
+// gets the engine's char, the first mover and the board with the engine's move if any.
+// It returns the moves made.
+func (e *Engine) Start(game *domain.Game, board domain.GameBoard, player domain.GameBoardChar, first domain.GamePlayer) (domain.GameMoves, error) {
+	// Create a new blank board of the same size.
+	b, err := domain.NewGameBoardOfSize(board.Size(), board.Win())
+	if err != nil {
+		return nil, errorx.WrapInBadRequest(err)
+	}
 	// Calc board difference.
 	d := b.Diff(board)
 
-	var c domain.GameBoardChar
 	switch len(d) {
 	// No move has been made.
 	case 0:
-		// Select char randomly.
-		if rand.Int31n(2) == 0 {
-			c = domain.GameBoardCharCross
-		} else {
-			c = domain.GameBoardCharNought
+		// Let the engine start unless the user wants to.
+		if first == "" {
+			first = domain.GamePlayerComputer
+		}
+		// Select char randomly unless the user has selected.
+		if player == "" {
+			if rand.Int31n(2) == 0 {
+				player = domain.GameBoardCharCross
+			} else {
+				player = domain.GameBoardCharNought
+			}
 		}
 	// One move has been made.
 	case 1:
-		// Select char other than user selected.
-		if board[d[0][0]][d[0][1]] == domain.GameBoardCharCross {
-			c = domain.GameBoardCharNought
-		} else {
-			c = domain.GameBoardCharCross
+		if first == domain.GamePlayerComputer {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("computer must make the first move"))
+		}
+		first = domain.GamePlayerHuman
+		// Use the char of the move unless the user has selected.
+		c := board.At(d[0][0], d[0][1])
+		if player == "" {
+			player = c
+		} else if c != player {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("move must be made with %s", player))
 		}
 	// More than one move was made.
 	default:
-		return "", domain.GameBoard{}, errorx.WrapInBadRequest(fmt.Errorf("invalid board diff"))
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("invalid board diff, set the side to move to start from the position"))
+	}
+
+	// Select char other than user selected.
+	game.Char = reverseChar(player)
+	game.FirstMover = first
+	game.Board = b
+	game.Start = b.Clone()
+
+	t := newTurn(game)
+	if len(d) == 1 {
+		// Apply the user's move.
+		err = t.apply(domain.GamePlayerHuman, d[0][0], d[0][1])
+		if err != nil {
+			return nil, err
+		}
+	} else if first == domain.GamePlayerHuman {
+		// Wait for the user's first move.
+		return nil, nil
 	}
 
-	u, err := e.move(board, c)
+	err = e.move(t)
 	if err != nil {
-		return "", domain.GameBoard{}, err
+		return nil, err
 	}
-	return c, u, nil
+	return t.moves, nil
 }
 
-func (e *Engine) Move(was, is domain.GameBoard, char domain.GameBoardChar) (domain.GameBoard, error) {
-	if was.IsFull() {
-		return domain.GameBoard{}, errorx.WrapInBadRequest(fmt.Errorf("board is full"))
+// StartFrom starts the game from the valid position on the board with the side to move.
+// The user plays with the player char, it is detected from the board if not set. The
+// game gets the engine's char, the starting position and the board with the engine's
+// move if the engine is to move. It returns the moves made.
+func (e *Engine) StartFrom(game *domain.Game, board domain.GameBoard, player domain.GameBoardChar, toMove domain.GamePlayer) (domain.GameMoves, error) {
+	if board.Winner() != domain.GameBoardCharNone {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("board has a winner"))
+	}
+	if board.IsFull() {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("board is full"))
+	}
+
+	// Detect the char to move by the number of pieces, the side having less pieces moves.
+	var c domain.GameBoardChar
+	x, o := 0, 0
+	for i := 0; i < board.Size(); i++ {
+		for j := 0; j < board.Size(); j++ {
+			switch board.At(i, j) {
+			case domain.GameBoardCharCross:
+				x++
+			case domain.GameBoardCharNought:
+				o++
+			}
+		}
+	}
+	switch {
+	case x < o:
+		c = domain.GameBoardCharCross
+	case o < x:
+		c = domain.GameBoardCharNought
+	case player == "":
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("player char is required when both sides have equal number of pieces"))
+	case toMove == domain.GamePlayerHuman:
+		c = player
+	default:
+		c = reverseChar(player)
 	}
 
-	if was.Winner() != domain.GameBoardCharNone {
-		return domain.GameBoard{}, errorx.WrapInBadRequest(fmt.Errorf("board has a winner"))
+	// Use the char to move unless the user has selected.
+	if toMove == domain.GamePlayerComputer {
+		c = reverseChar(c)
+	}
+	if player == "" {
+		player = c
+	} else if c != player {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("side to move doesn't match the board"))
+	}
+
+	// Check the position with the char to move.
+	if toMove == domain.GamePlayerComputer {
+		c = reverseChar(c)
+	}
+	err := board.Validate(c)
+	if err != nil {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("invalid board: %w", err))
+	}
+
+	// Select char other than user selected.
+	game.Char = reverseChar(player)
+	game.FirstMover = toMove
+	game.Board = board.Clone()
+	game.Start = board.Clone()
+
+	t := newTurn(game)
+	if toMove == domain.GamePlayerHuman {
+		// Wait for the user's move.
+		return nil, nil
+	}
+
+	err = e.move(t)
+	if err != nil {
+		return nil, err
+	}
+	return t.moves, nil
+}
+
+// Replay replays the moves on the game's starting position. The game must have the
+// starting position, the engine's char and the first mover set. The moves must alternate
+// the sides starting with the first mover, their chars must match the sides and no move
+// can be made once the game is over. The game gets the board and the status after the
+// last move, use Reply to make the engine's reply. It returns the replayed moves, their
+// times are kept.
+func (e *Engine) Replay(game *domain.Game, moves domain.GameMoves) (domain.GameMoves, error) {
+	if game.Start.Winner() != domain.GameBoardCharNone {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("start board has a winner"))
+	}
+	if game.FirstMover != domain.GamePlayerHuman && game.FirstMover != domain.GamePlayerComputer {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("invalid first mover"))
+	}
+	c := game.Char
+	if game.FirstMover == domain.GamePlayerHuman {
+		c = game.PlayerChar()
+	}
+	err := game.Start.Validate(c)
+	if err != nil {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("invalid start board: %w", err))
+	}
+
+	t, err := e.replay(game, moves)
+	if err != nil {
+		return nil, err
+	}
+	return t.moves, nil
+}
+
+// Reply makes the engine's move if the engine is to move in the game. The moves are the
+// moves made in the game. It returns the moves along with the engine's reply.
+func (e *Engine) Reply(game *domain.Game, moves domain.GameMoves) (domain.GameMoves, error) {
+	t := newTurn(game)
+	t.moves = moves
+	if t.over() || t.next() != domain.GamePlayerComputer {
+		return moves, nil
+	}
+	err := e.move(t)
+	if err != nil {
+		return nil, err
+	}
+	return t.moves, nil
+}
+
+// Undo takes back the user's last move along with the engine's replies on it. The moves
+// are the moves made in the game. The game gets the board and the status before the
+// user's last move. It returns the ply of the last move kept. A game ended by a resign,
+// an agreed draw or abandoning can't be undone, only the outcomes made on the board can.
+func (e *Engine) Undo(game *domain.Game, moves domain.GameMoves) (int, error) {
+	switch game.Status {
+	case domain.GameStatusResigned, domain.GameStatusDrawAgreed, domain.GameStatusAbandoned:
+		return 0, errorx.WrapInBadRequest(fmt.Errorf("game with status %s can't be undone", game.Status))
+	}
+	// Find the user's last move.
+	n := len(moves) - 1
+	for n >= 0 && moves[n].Player != domain.GamePlayerHuman {
+		n--
+	}
+	if n < 0 {
+		return 0, errorx.WrapInBadRequest(fmt.Errorf("no moves to undo"))
+	}
+
+	_, err := e.replay(game, moves[:n])
+	if err != nil {
+		return 0, err
+	}
+	return game.Start.Moves() + n, nil
+}
+
+// replay replays the moves on the game's starting position.
+func (e *Engine) replay(game *domain.Game, moves domain.GameMoves) (*turn, error) {
+	game.Board = game.Start.Clone()
+	game.Status = domain.GameStatusRunning
+	game.LastMover = ""
+	game.WinningMove = nil
+	game.WinningLine = nil
+	game.Winner = ""
+
+	t := newTurn(game)
+	for _, m := range moves {
+		p := t.next()
+		if m.Player != p {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("move %d must be made by %s", m.Ply, p))
+		}
+		if m.Cell < 0 || m.Cell >= game.Board.Size()*game.Board.Size() {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("move %d: invalid cell", m.Ply))
+		}
+		i, j := game.Board.Cell(m.Cell)
+		err := t.apply(p, i, j)
+		if err != nil {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("move %d: %w", m.Ply, err))
+		}
+		v := t.moves[len(t.moves)-1]
+		if m.Char != v.Char {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("move %d must be made with %s", m.Ply, v.Char))
+		}
+		if !m.Time.IsZero() {
+			v.Time = m.Time
+		}
+	}
+	return t, nil
+}
+
+// Resign ends the game in favour of the engine.
+func (e *Engine) Resign(game *domain.Game) error {
+	if newTurn(game).over() {
+		return errorx.WrapInBadRequest(fmt.Errorf("game is already over"))
+	}
+	game.Status = domain.GameStatusResigned
+	game.Winner = game.Char
+	return nil
+}
+
+// OfferDraw ends the game in a draw if the position is a draw with the perfect play of
+// both sides. It returns whether the offer is accepted.
+func (e *Engine) OfferDraw(game *domain.Game) (bool, error) {
+	t := newTurn(game)
+	if t.over() {
+		return false, errorx.WrapInBadRequest(fmt.Errorf("game is already over"))
+	}
+	c := game.Char
+	if t.next() == domain.GamePlayerHuman {
+		c = game.PlayerChar()
+	}
+	if EvaluatePosition(game.Board, c) != OutcomeDraw {
+		return false, nil
+	}
+	game.Status = domain.GameStatusDrawAgreed
+	return true, nil
+}
+
+// Move detects the user's move by the difference between the game board and the
+// board, makes it and responds with own move. It returns the moves made.
+func (e *Engine) Move(game *domain.Game, board domain.GameBoard) (domain.GameMoves, error) {
+	if game.Board.Size() != board.Size() {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("invalid board size"))
 	}
 
 	// Calc board difference.
-	d := was.Diff(is)
+	d := game.Board.Diff(board)
 
 	switch len(d) {
 	// One move has been made.
 	case 1:
-		c := is[d[0][0]][d[0][1]]
-		if c == domain.GameBoardCharNone /*|| c == char*/ {
-			return domain.GameBoard{}, errorx.WrapInBadRequest(fmt.Errorf("bad move"))
+		c := board.At(d[0][0], d[0][1])
+		if c == domain.GameBoardCharNone {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("bad move"))
 		}
-		// The API doesn't specify what the char (X or O) the user selected.
-		// So, let's allow to make a move with any char and simply fix if collides.
-		if c == char {
-			if c == domain.GameBoardCharCross {
-				is[d[0][0]][d[0][1]] = domain.GameBoardCharNought
-			} else {
-				is[d[0][0]][d[0][1]] = domain.GameBoardCharCross
-			}
+		if c != game.PlayerChar() {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("move must be made with %s", game.PlayerChar()))
 		}
+		return e.Play(game, d[0][0], d[0][1])
 	// None or more than one move was made.
 	default:
-		return domain.GameBoard{}, errorx.WrapInBadRequest(fmt.Errorf("invalid board diff"))
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("invalid board diff"))
 	}
+}
 
-	if is.IsFull() {
-		return is, nil
+// Play makes the user's move into the cell at row i and column j and responds with
+// own move unless the user's move has ended the game. It returns the moves made.
+func (e *Engine) Play(game *domain.Game, i, j int) (domain.GameMoves, error) {
+	t := newTurn(game)
+
+	err := t.apply(domain.GamePlayerHuman, i, j)
+	if err != nil {
+		return nil, err
 	}
 
-	u, err := e.move(is, char)
+	err = e.move(t)
 	if err != nil {
-		return domain.GameBoard{}, err
+		return nil, err
 	}
-	return u, nil
+	return t.moves, nil
 }
 
-func (e *Engine) move(board domain.GameBoard, char domain.GameBoardChar) (domain.GameBoard, error) {
+// move makes own move unless the game is over.
+func (e *Engine) move(t *turn) error {
+	if t.over() {
+		return nil
+	}
+
 	// Detect best move.
-	i, j := e.s.BestMove(board, char)
+	i, j := e.s.BestMove(t.game.Board, t.game.Char)
 
 	// Ensure that selected call is not used.
-	if board[i][j] != domain.GameBoardCharNone {
-		return domain.GameBoard{}, fmt.Errorf("used cell chosen")
+	if t.game.Board.At(i, j) != domain.GameBoardCharNone {
+		return fmt.Errorf("used cell chosen")
 	}
 
 	// Make move.
-	board[i][j] = char
-
-	return board, nil
+	return t.apply(domain.GamePlayerComputer, i, j)
 }
@@ -0,0 +1,95 @@
+package game
+
+import (
+	"math"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
+
+// Outcome is a game-theoretic value of a move.
+type Outcome string
+
+const (
+	// OutcomeWin means that the move leads to a forced win.
+	OutcomeWin Outcome = "WIN"
+	// OutcomeDraw means that the move leads to a draw with the perfect play of both sides.
+	OutcomeDraw Outcome = "DRAW"
+	// OutcomeLoss means that the move leads to a forced loss.
+	OutcomeLoss Outcome = "LOSS"
+	// OutcomeUnknown means that the board is too big to search the move completely and
+	// no forced result has been found.
+	OutcomeUnknown Outcome = "UNKNOWN"
+)
+
+// Evaluation is a game-theoretic evaluation of a move.
+type Evaluation struct {
+	Row int
+	Col int
+	// Outcome is the result of the move with the perfect play of both sides.
+	Outcome Outcome
+	// Plies is the number of moves of both sides, including the evaluated one, until
+	// the outcome. It is 0 if the outcome is unknown.
+	Plies int
+}
+
+// Evaluate evaluates every free cell of the board for char with the MiniMax algorithm.
+// Evaluations are ordered by the cell.
+func Evaluate(board domain.GameBoard, char domain.GameBoardChar) []*Evaluation {
+	var s []*Evaluation
+	for _, i := range freeCells(board) {
+		s = append(s, EvaluateMove(board, char, i.i, i.j))
+	}
+	return s
+}
+
+// EvaluatePosition evaluates the position on the board for char to move. It is the best
+// outcome of the moves.
+func EvaluatePosition(board domain.GameBoard, char domain.GameBoardChar) Outcome {
+	r := OutcomeLoss
+	for _, i := range Evaluate(board, char) {
+		switch {
+		case i.Outcome == OutcomeWin:
+			return OutcomeWin
+		case i.Outcome == OutcomeUnknown:
+			r = OutcomeUnknown
+		case i.Outcome == OutcomeDraw && r == OutcomeLoss:
+			r = OutcomeDraw
+		}
+	}
+	return r
+}
+
+// EvaluateMove evaluates the move of char into the free cell at row i and column j
+// with the MiniMax algorithm.
+func EvaluateMove(board domain.GameBoard, char domain.GameBoardChar, i, j int) *Evaluation {
+	m := &minimaxStrategy{}
+
+	// Work on a copy to keep the caller's board untouched.
+	board = board.Clone()
+	f := len(freeCells(board))
+	d := m.depth(board)
+
+	board.Set(i, j, char)
+	r := m.minimax(board, d-1, false, char, math.Inf(-1), math.Inf(1))
+
+	v := &Evaluation{
+		Row: i,
+		Col: j,
+	}
+	switch {
+	case r > 0:
+		// The rating of the finished game is the number of free cells left plus one.
+		v.Outcome = OutcomeWin
+		v.Plies = f - int(r) + 1
+	case r < 0:
+		v.Outcome = OutcomeLoss
+		v.Plies = f + int(r) + 1
+	case d >= f:
+		// The draw is only possible on the full board.
+		v.Outcome = OutcomeDraw
+		v.Plies = f
+	default:
+		v.Outcome = OutcomeUnknown
+	}
+	return v
+}
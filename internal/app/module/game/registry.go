@@ -0,0 +1,65 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+)
+
+const (
+	// StrategyRandom moves randomly.
+	StrategyRandom domain.GameStrategy = "random"
+	// StrategyEasy takes and blocks immediate wins only.
+	StrategyEasy domain.GameStrategy = "easy"
+	// StrategyMedium looks a couple of moves ahead.
+	StrategyMedium domain.GameStrategy = "medium"
+	// StrategyPerfect never loses.
+	StrategyPerfect domain.GameStrategy = "perfect"
+	// StrategyMCTS rates moves statistically, it scales to big boards.
+	StrategyMCTS domain.GameStrategy = "mcts"
+
+	// StrategyDefault is used unless another Strategy is selected.
+	StrategyDefault = StrategyPerfect
+)
+
+// Registry keeps named Strategy implementations.
+type Registry struct {
+	m map[domain.GameStrategy]Strategy
+}
+
+// NewRegistry creates a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		m: make(map[domain.GameStrategy]Strategy),
+	}
+}
+
+// NewDefaultRegistry creates a new Registry of all the known strategies using rand
+// to choose between equally good moves.
+func NewDefaultRegistry(rand Rand) *Registry {
+	r := NewRegistry()
+	r.Register(StrategyRandom, NewRandomStrategy(rand))
+	r.Register(StrategyEasy, NewEasyStrategy(rand))
+	r.Register(StrategyMedium, NewLimitedMinimaxStrategy(2, rand))
+	r.Register(StrategyPerfect, NewTableStrategy(rand))
+	r.Register(StrategyMCTS, NewMCTSStrategy(100_000, time.Second, rand))
+	return r
+}
+
+// Register registers the Strategy under the name. It replaces the Strategy
+// registered under the same name if any.
+func (r *Registry) Register(name domain.GameStrategy, strategy Strategy) {
+	r.m[name] = strategy
+}
+
+// Get returns the Strategy registered under the name. It returns errorx.BadRequest
+// if there is no such Strategy.
+func (r *Registry) Get(name domain.GameStrategy) (Strategy, error) {
+	s, ok := r.m[name]
+	if !ok {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("unknown strategy: %s", name))
+	}
+	return s, nil
+}
@@ -4,31 +4,105 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/mgrabazey/tic-tac-toe/internal/domain"
 	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
 	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo"
 )
 
+// DefaultUndoLimit is the number of times the user can take back a move unless set.
+const DefaultUndoLimit = 3
+
 type CreateRequest struct {
 	Board domain.GameBoard
+	// Char is the user's char. It is detected from the board or selected randomly if empty.
+	Char domain.GameBoardChar
+	// FirstMover is the side making the first move. It is detected from the board if empty.
+	FirstMover domain.GamePlayer
+	// Strategy is the computer's strategy. StrategyDefault is used if empty.
+	Strategy domain.GameStrategy
+	// ToMove is the side to move in the position on the board. If set, the game starts
+	// from any valid position and FirstMover must be empty.
+	ToMove domain.GamePlayer
+	// UndoLimit is the number of times the user can take back a move. DefaultUndoLimit
+	// is used if nil.
+	UndoLimit *int
 }
 
 type UpdateRequest struct {
 	Id    domain.GameId
 	Board domain.GameBoard
+	// Version is the version of the game the update is based on. If set, the game is
+	// updated only if its version matches.
+	Version *int
+}
+
+type MoveRequest struct {
+	Id domain.GameId
+	// Cell is the index of the cell to move into. Row and Col are used if Cell is nil.
+	Cell *int
+	Row  int
+	Col  int
+	// Version is the version of the game the move is based on. If set, the move is
+	// made only if the game's version matches.
+	Version *int
+}
+
+type MoveResult struct {
+	Game *domain.Game
+	// Reply is the index of the cell of the computer's reply move or nil if
+	// the computer didn't move.
+	Reply *int
+	// Move is the number of the user's move counting both sides.
+	Move int
+}
+
+type HintResult struct {
+	Game *domain.Game
+	// Cell is the index of the cell suggested to move into.
+	Cell       int
+	Evaluation *Evaluation
+}
+
+type AnalyzeRequest struct {
+	Board domain.GameBoard
+	// Char is the char of the side to move.
+	Char domain.GameBoardChar
+}
+
+type DrawOfferResult struct {
+	Game *domain.Game
+	// Accepted is whether the computer has accepted the draw offer.
+	Accepted bool
+}
+
+type ForkRequest struct {
+	Id domain.GameId
+	// Ply is the ply of the last move to keep. The moves made after it are dropped.
+	Ply int
+}
+
+type ImportRequest struct {
+	// Game is the game to import. It must have the starting position, the computer's
+	// char and the first mover set. A new identifier is generated if empty.
+	Game *domain.Game
+	// Moves are the moves made on the starting position.
+	Moves domain.GameMoves
+	// UndoLimit is the number of times the user can take back a move. DefaultUndoLimit
+	// is used if nil.
+	UndoLimit *int
 }
 
 type Service struct {
 	r repo.GameRepository
-	e *Engine
+	s *Registry
 }
 
-func NewService(repo repo.GameRepository) *Service {
+func NewService(repo repo.GameRepository, strategies *Registry) *Service {
 	return &Service{
 		r: repo,
-		// Not the best solution, but good enough since we only have one strategy.
-		e: NewEngine(NewMinimaxStrategy()),
+		s: strategies,
 	}
 }
 
@@ -55,18 +129,38 @@ func (s *Service) Get(ctx context.Context, id domain.GameId) (*domain.Game, erro
 func (s *Service) Create(ctx context.Context, request *CreateRequest) (*domain.Game, error) {
 	// Create new game.
 	g := &domain.Game{
-		Id:     domain.NewGameId(),
-		Status: domain.GameStatusRunning,
+		Id:        domain.NewGameId(),
+		Status:    domain.GameStatusRunning,
+		Strategy:  request.Strategy,
+		UndoLimit: DefaultUndoLimit,
+		Created:   time.Now(),
+	}
+	if g.Strategy == "" {
+		g.Strategy = StrategyDefault
+	}
+	if request.UndoLimit != nil {
+		if *request.UndoLimit < 0 {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("invalid undo limit"))
+		}
+		g.UndoLimit = *request.UndoLimit
+	}
+	e, err := s.engine(g)
+	if err != nil {
+		return nil, err
 	}
 	// Check user's move if any and make own.
-	var err error
-	g.Char, g.Board, err = s.e.Start(request.Board)
+	var m domain.GameMoves
+	if request.ToMove != "" {
+		m, err = e.StartFrom(g, request.Board, request.Char, request.ToMove)
+	} else {
+		m, err = e.Start(g, request.Board, request.Char, request.FirstMover)
+	}
 	if err != nil {
 		log.Printf("Unable to start game: %v\n", err)
 		return nil, err
 	}
 	// Create game
-	err = s.r.Create(ctx, g)
+	err = s.r.Create(ctx, g, m)
 	if err != nil {
 		log.Printf("Unable to create game: %v\n", err)
 		return nil, err
@@ -80,29 +174,187 @@ func (s *Service) Update(ctx context.Context, request *UpdateRequest) (*domain.G
 	if err != nil {
 		return nil, err
 	}
+	// Throw if game has been changed since the user has seen it.
+	err = checkVersion(g, request.Version)
+	if err != nil {
+		return nil, err
+	}
 	// Throw if game is already over.
 	if g.Status != domain.GameStatusRunning {
 		return nil, errorx.WrapInBadRequest(fmt.Errorf("game is already over"))
 	}
+	e, err := s.engine(g)
+	if err != nil {
+		return nil, err
+	}
 	// Check user's move and make own.
-	g.Board, err = s.e.Move(g.Board, request.Board, g.Char)
+	m, err := e.Move(g, request.Board)
 	if err != nil {
 		log.Printf("Unable to move: %v\n", err)
 		return nil, err
 	}
-	// Check if game is already over.
-	switch g.Board.Winner() {
-	case domain.GameBoardCharCross:
-		g.Status = domain.GameStatusCrossWon
-	case domain.GameBoardCharNought:
-		g.Status = domain.GameStatusNoughtWon
-	default:
-		if g.Board.IsFull() {
-			g.Status = domain.GameStatusDraw
+	// Update game,
+	err = s.r.Update(ctx, g, m)
+	if err != nil {
+		log.Printf("Unable to update game: %v\n", err)
+		return nil, err
+	}
+	return g, nil
+}
+
+func (s *Service) Move(ctx context.Context, request *MoveRequest) (*MoveResult, error) {
+	// Get game by identifier.
+	g, err := s.r.Get(ctx, request.Id)
+	if err != nil {
+		return nil, err
+	}
+	// Throw if game has been changed since the user has seen it.
+	err = checkVersion(g, request.Version)
+	if err != nil {
+		return nil, err
+	}
+	// Throw if game is already over.
+	if g.Status != domain.GameStatusRunning {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("game is already over"))
+	}
+	// Resolve the cell coordinates.
+	i, j := request.Row, request.Col
+	if request.Cell != nil {
+		if *request.Cell < 0 || *request.Cell >= g.Board.Size()*g.Board.Size() {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("invalid cell"))
 		}
+		i, j = g.Board.Cell(*request.Cell)
+	}
+	e, err := s.engine(g)
+	if err != nil {
+		return nil, err
+	}
+	// Make user's move and own.
+	m, err := e.Play(g, i, j)
+	if err != nil {
+		log.Printf("Unable to move: %v\n", err)
+		return nil, err
 	}
 	// Update game,
-	err = s.r.Update(ctx, g)
+	err = s.r.Update(ctx, g, m)
+	if err != nil {
+		log.Printf("Unable to update game: %v\n", err)
+		return nil, err
+	}
+	// Find own reply move.
+	v := &MoveResult{
+		Game: g,
+		Move: m[0].Ply,
+	}
+	for _, i := range m {
+		if i.Player == domain.GamePlayerComputer {
+			v.Reply = &i.Cell
+		}
+	}
+	return v, nil
+}
+
+// Import replays the moves of the games and creates them. The status of a game, if set,
+// must match the status after the last move, before the computer replies if it is to
+// move. Nothing is created unless every game is valid.
+func (s *Service) Import(ctx context.Context, requests []*ImportRequest) (domain.Games, error) {
+	var (
+		v   domain.Games
+		m   []domain.GameMoves
+		ids = make(map[domain.GameId]bool, len(requests))
+	)
+	for n, i := range requests {
+		g := i.Game
+		if g.Id == "" {
+			g.Id = domain.NewGameId()
+		}
+		if g.Created.IsZero() {
+			g.Created = time.Now()
+		}
+		if g.Strategy == "" {
+			g.Strategy = StrategyDefault
+		}
+		g.UndoLimit = DefaultUndoLimit
+		if i.UndoLimit != nil {
+			if *i.UndoLimit < 0 {
+				return nil, errorx.WrapInBadRequest(fmt.Errorf("game %d: undo limit can't be negative", n+1))
+			}
+			g.UndoLimit = *i.UndoLimit
+		}
+		// Throw if the id is repeated, the repo throws if game already exists.
+		if ids[g.Id] {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("game %d: duplicate id", n+1))
+		}
+		ids[g.Id] = true
+		e, err := s.engine(g)
+		if err != nil {
+			return nil, err
+		}
+		// Replay the moves.
+		r := g.Status
+		k, err := e.Replay(g, i.Moves)
+		if err != nil {
+			log.Printf("Unable to replay game: %v\n", err)
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("game %d: %w", n+1, err))
+		}
+		// Replay the game's ending unless made by the moves.
+		switch r {
+		case "", g.Status:
+		case domain.GameStatusResigned:
+			err = e.Resign(g)
+		case domain.GameStatusDrawAgreed:
+			var ok bool
+			ok, err = e.OfferDraw(g)
+			if err == nil && !ok {
+				err = fmt.Errorf("draw offer is declined")
+			}
+		case domain.GameStatusAbandoned:
+			if g.Status != domain.GameStatusRunning {
+				err = fmt.Errorf("game is already over")
+				break
+			}
+			g.Status = domain.GameStatusAbandoned
+		default:
+			err = fmt.Errorf("status %s doesn't match moves", r)
+		}
+		if err != nil {
+			return nil, errorx.WrapInBadRequest(fmt.Errorf("game %d: %w", n+1, err))
+		}
+		// Reply to the user's last move.
+		k, err = e.Reply(g, k)
+		if err != nil {
+			log.Printf("Unable to move: %v\n", err)
+			return nil, err
+		}
+		v = append(v, g)
+		m = append(m, k)
+	}
+	// Create games.
+	err := s.r.CreateAll(ctx, v, m)
+	if err != nil {
+		log.Printf("Unable to create games: %v\n", err)
+		return nil, err
+	}
+	return v, nil
+}
+
+// Resign ends the game in favour of the computer.
+func (s *Service) Resign(ctx context.Context, id domain.GameId) (*domain.Game, error) {
+	// Get game by identifier.
+	g, err := s.r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	e, err := s.engine(g)
+	if err != nil {
+		return nil, err
+	}
+	err = e.Resign(g)
+	if err != nil {
+		return nil, err
+	}
+	// Update game.
+	err = s.r.Update(ctx, g, nil)
 	if err != nil {
 		log.Printf("Unable to update game: %v\n", err)
 		return nil, err
@@ -110,6 +362,210 @@ func (s *Service) Update(ctx context.Context, request *UpdateRequest) (*domain.G
 	return g, nil
 }
 
+// OfferDraw offers the computer a draw. The computer accepts the offer if the position
+// is a draw with the perfect play of both sides.
+func (s *Service) OfferDraw(ctx context.Context, id domain.GameId) (*DrawOfferResult, error) {
+	// Get game by identifier.
+	g, err := s.r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	e, err := s.engine(g)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := e.OfferDraw(g)
+	if err != nil {
+		return nil, err
+	}
+	// Update game if the offer is accepted.
+	if ok {
+		err = s.r.Update(ctx, g, nil)
+		if err != nil {
+			log.Printf("Unable to update game: %v\n", err)
+			return nil, err
+		}
+	}
+	return &DrawOfferResult{
+		Game:     g,
+		Accepted: ok,
+	}, nil
+}
+
+// Fork creates a new game from the position after the ply of the game. The new game
+// keeps the chars, the strategy and the undo limit of the game and links back to it.
+// The computer replies if it is to move in the position.
+func (s *Service) Fork(ctx context.Context, request *ForkRequest) (*domain.Game, error) {
+	// Get game by identifier.
+	p, err := s.r.Get(ctx, request.Id)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.r.Moves(ctx, request.Id)
+	if err != nil {
+		return nil, err
+	}
+	// Throw if there is no such ply.
+	n := request.Ply - p.Start.Moves()
+	if n < 0 || n > len(m) {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("invalid ply"))
+	}
+	// Create new game.
+	g := &domain.Game{
+		Id:         domain.NewGameId(),
+		Start:      p.Start.Clone(),
+		Char:       p.Char,
+		FirstMover: p.FirstMover,
+		Strategy:   p.Strategy,
+		UndoLimit:  p.UndoLimit,
+		Created:    time.Now(),
+		Parent: &domain.GameParent{
+			Id:  p.Id,
+			Ply: request.Ply,
+		},
+	}
+	e, err := s.engine(g)
+	if err != nil {
+		return nil, err
+	}
+	// Replay the moves made before the fork.
+	m, err = e.Replay(g, m[:n])
+	if err != nil {
+		log.Printf("Unable to replay game: %v\n", err)
+		return nil, err
+	}
+	m, err = e.Reply(g, m)
+	if err != nil {
+		log.Printf("Unable to move: %v\n", err)
+		return nil, err
+	}
+	// Create game
+	err = s.r.Create(ctx, g, m)
+	if err != nil {
+		log.Printf("Unable to create game: %v\n", err)
+		return nil, err
+	}
+	return g, nil
+}
+
+// Undo takes back the user's last move along with the computer's reply on it.
+func (s *Service) Undo(ctx context.Context, id domain.GameId) (*domain.Game, error) {
+	// Get game by identifier.
+	g, err := s.r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Throw if the user has run out of undos.
+	if g.Undos >= g.UndoLimit {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("undo limit is reached"))
+	}
+	m, err := s.r.Moves(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	e, err := s.engine(g)
+	if err != nil {
+		return nil, err
+	}
+	// Take back the moves.
+	p, err := e.Undo(g, m)
+	if err != nil {
+		log.Printf("Unable to undo: %v\n", err)
+		return nil, err
+	}
+	// Count undos.
+	g.Undos++
+	// Update game.
+	err = s.r.Rewind(ctx, g, p)
+	if err != nil {
+		log.Printf("Unable to update game: %v\n", err)
+		return nil, err
+	}
+	return g, nil
+}
+
+// Abandon marks the running games having no move for the idle period as abandoned.
+// It returns the number of games marked.
+func (s *Service) Abandon(ctx context.Context, idle time.Duration) (int, error) {
+	n, err := s.r.Abandon(ctx, time.Now().Add(-idle))
+	if err != nil {
+		log.Printf("Unable to abandon games: %v\n", err)
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *Service) Moves(ctx context.Context, id domain.GameId) (domain.GameMoves, error) {
+	// Get game moves by game identifier.
+	v, err := s.r.Moves(ctx, id)
+	if err != nil {
+		log.Printf("Unable to get game moves: %v\n", err)
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *Service) Hint(ctx context.Context, id domain.GameId) (*HintResult, error) {
+	// Get game by identifier.
+	g, err := s.r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Throw if game is already over.
+	if g.Status != domain.GameStatusRunning {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("game is already over"))
+	}
+	v, err := s.s.Get(g.Strategy)
+	if err != nil {
+		log.Printf("Unable to get strategy: %v\n", err)
+		return nil, err
+	}
+	// Find the best move for the user.
+	i, j := v.BestMove(g.Board, g.PlayerChar())
+	// Count hints.
+	g.Hints++
+	err = s.r.Update(ctx, g, nil)
+	if err != nil {
+		log.Printf("Unable to update game: %v\n", err)
+		return nil, err
+	}
+	return &HintResult{
+		Game:       g,
+		Cell:       g.Board.Index(i, j),
+		Evaluation: EvaluateMove(g.Board, g.PlayerChar(), i, j),
+	}, nil
+}
+
+func (s *Service) Analyze(ctx context.Context, request *AnalyzeRequest) ([]*Evaluation, error) {
+	// Throw if there are no moves to analyze.
+	if request.Board.Winner() != domain.GameBoardCharNone {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("board has a winner"))
+	}
+	if request.Board.IsFull() {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("board is full"))
+	}
+	// Evaluate every free cell.
+	return Evaluate(request.Board, request.Char), nil
+}
+
+// checkVersion checks that the game's version matches the version if set.
+func checkVersion(game *domain.Game, version *int) error {
+	if version != nil && *version != game.Version {
+		return errorx.WrapInConflict(fmt.Errorf("game version doesn't match"))
+	}
+	return nil
+}
+
+// engine creates an Engine playing the game's strategy.
+func (s *Service) engine(game *domain.Game) (*Engine, error) {
+	v, err := s.s.Get(game.Strategy)
+	if err != nil {
+		log.Printf("Unable to get strategy: %v\n", err)
+		return nil, err
+	}
+	return NewEngine(v), nil
+}
+
 func (s *Service) Delete(ctx context.Context, id domain.GameId) error {
 	// Delete game by identifier.
 	err := s.r.Delete(ctx, id)
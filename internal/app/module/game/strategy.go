@@ -2,6 +2,8 @@ package game
 
 import (
 	"math"
+	"math/rand"
+	"time"
 
 	"github.com/mgrabazey/tic-tac-toe/internal/domain"
 )
@@ -12,54 +14,166 @@ type Strategy interface {
 	BestMove(board domain.GameBoard, char domain.GameBoardChar) (int, int)
 }
 
-type minimaxStrategy struct{}
+// Rand is a source of random numbers used by strategies to choose between equally good moves.
+type Rand interface {
+	// Intn returns a non-negative pseudo-random number in [0,n).
+	Intn(n int) int
+}
+
+// DefaultRand is the Rand safe for concurrent use backed by the math/rand default source.
+var DefaultRand Rand = defaultRand{}
+
+type defaultRand struct{}
+
+func (defaultRand) Intn(n int) int {
+	return rand.Intn(n)
+}
+
+type randomStrategy struct {
+	r Rand
+}
+
+// NewRandomStrategy creates a new Strategy which moves into a random free cell.
+func NewRandomStrategy(rand Rand) Strategy {
+	return &randomStrategy{
+		r: rand,
+	}
+}
+
+func (s *randomStrategy) BestMove(board domain.GameBoard, char domain.GameBoardChar) (int, int) {
+	f := freeCells(board)
+	i := f[s.r.Intn(len(f))]
+	return i.i, i.j
+}
+
+type easyStrategy struct {
+	r Rand
+}
+
+// NewEasyStrategy creates a new Strategy which takes an immediate win if any, blocks
+// the rival's immediate win if any and moves into a random free cell otherwise.
+func NewEasyStrategy(rand Rand) Strategy {
+	return &easyStrategy{
+		r: rand,
+	}
+}
+
+func (s *easyStrategy) BestMove(board domain.GameBoard, char domain.GameBoardChar) (int, int) {
+	// Work on a copy to keep the caller's board untouched.
+	board = board.Clone()
+	f := freeCells(board)
+
+	for _, c := range []domain.GameBoardChar{char, reverseChar(char)} {
+		for _, i := range f {
+			// Check if the move completes a line.
+			board.Set(i.i, i.j, c)
+			w := board.Winner()
+			board.Set(i.i, i.j, domain.GameBoardCharNone)
+			if w != domain.GameBoardCharNone {
+				return i.i, i.j
+			}
+		}
+	}
+
+	i := f[s.r.Intn(len(f))]
+	return i.i, i.j
+}
+
+// minimaxLeafLimit limits the number of leaf positions the minimax search may visit.
+// The classic 3x3 board (9! positions) is searched completely, bigger boards are
+// searched up to the depth that keeps the number of positions within the limit.
+const minimaxLeafLimit = 2_000_000
+
+type minimaxStrategy struct {
+	limit int
+	r     Rand
+}
 
 // NewMinimaxStrategy creates a new MiniMax Strategy. The strategy is aimed at minimizing possible losses.
 // The algorithm is described here https://en.wikipedia.org/wiki/Minimax#Example
-func NewMinimaxStrategy() Strategy {
-	return &minimaxStrategy{}
+// Quicker wins and slower losses are rated higher, one of equally good moves is chosen randomly.
+func NewMinimaxStrategy(rand Rand) Strategy {
+	return &minimaxStrategy{
+		r: rand,
+	}
+}
+
+// NewLimitedMinimaxStrategy creates a new MiniMax Strategy which looks not more than depth
+// moves ahead. Outcomes beyond the depth are considered as draw.
+func NewLimitedMinimaxStrategy(depth int, rand Rand) Strategy {
+	return &minimaxStrategy{
+		limit: depth,
+		r:     rand,
+	}
+}
+
+// OptimalMoves provides all the equally best moves on domain.GameBoard for domain.GameBoardChar
+// according to the perfect play. Each move is a pair of the row and the column.
+func OptimalMoves(board domain.GameBoard, char domain.GameBoardChar) [][2]int {
+	var s [][2]int
+	for _, i := range (&minimaxStrategy{}).bestMoves(board, char) {
+		s = append(s, [2]int{i.i, i.j})
+	}
+	return s
 }
 
 func (s *minimaxStrategy) BestMove(board domain.GameBoard, char domain.GameBoardChar) (int, int) {
-	var i, j int
+	m := s.bestMoves(board, char)
+	// Break ties randomly.
+	i := m[s.r.Intn(len(m))]
+	return i.i, i.j
+}
+
+// bestMoves provides all the moves having the best rating.
+func (s *minimaxStrategy) bestMoves(board domain.GameBoard, char domain.GameBoardChar) []cell {
+	var m []cell
+
+	// Work on a copy to keep the caller's board untouched.
+	board = board.Clone()
+	// Limit search depth for big boards.
+	depth := s.depth(board)
+	if s.limit > 0 && s.limit < depth {
+		depth = s.limit
+	}
 
 	// Set initial rating value as negative infinity.
 	b := math.Inf(-1)
 
-	for i1 := 0; i1 < 3; i1++ {
-		for j1 := 0; j1 < 3; j1++ {
-			// Consider not used sells only.
-			if board[i1][j1] == domain.GameBoardCharNone {
-				// Make a move.
-				board[i1][j1] = char
-				// Get move rating.
-				r := s.minimax(board, false, char, math.Inf(-1), math.Inf(1))
-				// Rollback board state.
-				board[i1][j1] = domain.GameBoardCharNone
-
-				// Update best rating if current rating is better.
-				if r > b {
-					b = r
-					i, j = i1, j1
-				}
-			}
+	for _, i := range freeCells(board) {
+		// Make a move.
+		board.Set(i.i, i.j, char)
+		// Get move rating. Use the full window to get the exact rating of every move.
+		r := s.minimax(board, depth-1, false, char, math.Inf(-1), math.Inf(1))
+		// Rollback board state.
+		board.Set(i.i, i.j, domain.GameBoardCharNone)
+
+		// Update best rating if current rating is better.
+		if r > b {
+			b = r
+			m = m[:0]
+		}
+		if r == b {
+			m = append(m, i)
 		}
 	}
 
-	return i, j
+	return m
 }
 
-func (s *minimaxStrategy) minimax(board domain.GameBoard, maximizing bool, char domain.GameBoardChar, alpha float64, beta float64) float64 {
+func (s *minimaxStrategy) minimax(board domain.GameBoard, depth int, maximizing bool, char domain.GameBoardChar, alpha float64, beta float64) float64 {
 	w := board.Winner()
 	if w != domain.GameBoardCharNone {
+		// The more free cells are left, the quicker the game has ended.
+		r := float64(board.Size()*board.Size() - board.Moves() + 1)
 		if w == char {
-			return 1 // Won :)
+			return r // Won :)
 		} else {
-			return -1 // Rival won :(
+			return -r // Rival won :(
 		}
 	}
 
-	if board.IsFull() {
+	// Nobody wins on full board and the outcome is unknown when the depth is exhausted.
+	if board.IsFull() || depth == 0 {
 		return 0
 	}
 
@@ -67,16 +181,16 @@ func (s *minimaxStrategy) minimax(board domain.GameBoard, maximizing bool, char
 		// Set initial rating value as negative infinity.
 		b := math.Inf(-1)
 
-		for i := 0; i < 3; i++ {
-			for j := 0; j < 3; j++ {
+		for i := 0; i < board.Size(); i++ {
+			for j := 0; j < board.Size(); j++ {
 				// Consider not used sells only.
-				if board[i][j] == domain.GameBoardCharNone {
+				if board.At(i, j) == domain.GameBoardCharNone {
 					// Make a move.
-					board[i][j] = char
+					board.Set(i, j, char)
 					// Get move rating.
-					r := s.minimax(board, false, char, alpha, beta)
+					r := s.minimax(board, depth-1, false, char, alpha, beta)
 					// Rollback board state.
-					board[i][j] = domain.GameBoardCharNone
+					board.Set(i, j, domain.GameBoardCharNone)
 
 					// Update best rating if current rating is better.
 					b = math.Max(b, r)
@@ -94,17 +208,17 @@ func (s *minimaxStrategy) minimax(board domain.GameBoard, maximizing bool, char
 		// Set initial rating value as positive infinity.
 		b := math.Inf(1)
 
-		for i := 0; i < 3; i++ {
-			for j := 0; j < 3; j++ {
+		for i := 0; i < board.Size(); i++ {
+			for j := 0; j < board.Size(); j++ {
 				// Consider not used sells only.
-				if board[i][j] == domain.GameBoardCharNone {
-					c := s.reverseChar(char)
+				if board.At(i, j) == domain.GameBoardCharNone {
+					c := reverseChar(char)
 					// Make a move.
-					board[i][j] = c
+					board.Set(i, j, c)
 					// Get move rating.
-					r := s.minimax(board, true, char, alpha, beta)
+					r := s.minimax(board, depth-1, true, char, alpha, beta)
 					// Rollback board state.
-					board[i][j] = domain.GameBoardCharNone
+					board.Set(i, j, domain.GameBoardCharNone)
 
 					// Update best rating if current rating is worse.
 					b = math.Min(b, r)
@@ -121,7 +235,187 @@ func (s *minimaxStrategy) minimax(board domain.GameBoard, maximizing bool, char
 	}
 }
 
-func (s *minimaxStrategy) reverseChar(char domain.GameBoardChar) domain.GameBoardChar {
+// depth calculates the search depth which keeps the number of visited leaf positions
+// within minimaxLeafLimit.
+func (s *minimaxStrategy) depth(board domain.GameBoard) int {
+	e := 0
+	for i := 0; i < board.Size(); i++ {
+		for j := 0; j < board.Size(); j++ {
+			if board.At(i, j) == domain.GameBoardCharNone {
+				e++
+			}
+		}
+	}
+	d, n := 0, 1
+	for d < e && n*(e-d) <= minimaxLeafLimit {
+		n *= e - d
+		d++
+	}
+	return d
+}
+
+type mctsStrategy struct {
+	iterations int
+	budget     time.Duration
+	r          Rand
+}
+
+// NewMCTSStrategy creates a new Monte Carlo Tree Search Strategy. The strategy grows the
+// game tree by the UCT rule and rates the moves by random playouts. The search stops after
+// iterations playouts or once the budget is spent, whichever comes first, a zero value
+// disables the limit. The strategy is deterministic for a seeded rand as long as it is
+// limited by iterations only.
+// The algorithm is described here https://en.wikipedia.org/wiki/Monte_Carlo_tree_search
+func NewMCTSStrategy(iterations int, budget time.Duration, rand Rand) Strategy {
+	return &mctsStrategy{
+		iterations: iterations,
+		budget:     budget,
+		r:          rand,
+	}
+}
+
+// mctsExploration is the UCT exploration parameter.
+var mctsExploration = math.Sqrt2
+
+type mctsNode struct {
+	parent   *mctsNode
+	children []*mctsNode
+	// untried are the moves not expanded yet.
+	untried []cell
+	// move is the move leading to the node made with char.
+	move cell
+	char domain.GameBoardChar
+	// visits is the number of playouts through the node, wins is the number of them won
+	// by char, draws are counted as half of win.
+	visits float64
+	wins   float64
+}
+
+func (s *mctsStrategy) BestMove(board domain.GameBoard, char domain.GameBoardChar) (int, int) {
+	root := &mctsNode{
+		untried: freeCells(board),
+		char:    reverseChar(char),
+	}
+
+	t := time.Now()
+	for n := 0; ; n++ {
+		if s.iterations > 0 && n >= s.iterations {
+			break
+		}
+		if s.budget > 0 && time.Since(t) >= s.budget {
+			break
+		}
+		// Make at least one playout.
+		if s.iterations <= 0 && s.budget <= 0 && n > 0 {
+			break
+		}
+
+		b := board.Clone()
+		v := root
+
+		// Select the most promising node.
+		for len(v.untried) == 0 && len(v.children) > 0 {
+			v = s.selectChild(v)
+			b.Set(v.move.i, v.move.j, v.char)
+		}
+
+		// Expand the node with a random untried move.
+		if len(v.untried) > 0 {
+			k := s.r.Intn(len(v.untried))
+			m := v.untried[k]
+			v.untried = append(v.untried[:k:k], v.untried[k+1:]...)
+
+			c := reverseChar(v.char)
+			b.Set(m.i, m.j, c)
+			u := &mctsNode{
+				parent: v,
+				move:   m,
+				char:   c,
+			}
+			if b.Winner() == domain.GameBoardCharNone {
+				u.untried = freeCells(b)
+			}
+			v.children = append(v.children, u)
+			v = u
+		}
+
+		// Play randomly until the game is over.
+		w := s.playout(b, reverseChar(v.char))
+
+		// Propagate the result up to the root.
+		for ; v != nil; v = v.parent {
+			v.visits++
+			switch w {
+			case v.char:
+				v.wins++
+			case domain.GameBoardCharNone:
+				v.wins += 0.5
+			}
+		}
+	}
+
+	// Choose the most visited move.
+	var b *mctsNode
+	for _, i := range root.children {
+		if b == nil || i.visits > b.visits {
+			b = i
+		}
+	}
+	return b.move.i, b.move.j
+}
+
+// selectChild selects the child node by the UCT rule.
+func (s *mctsStrategy) selectChild(node *mctsNode) *mctsNode {
+	var (
+		b *mctsNode
+		r float64
+	)
+	l := math.Log(node.visits)
+	for _, i := range node.children {
+		u := i.wins/i.visits + mctsExploration*math.Sqrt(l/i.visits)
+		if b == nil || u > r {
+			b, r = i, u
+		}
+	}
+	return b
+}
+
+// playout makes random moves starting with char until the game is over and returns
+// the winner's char or domain.GameBoardCharNone in case of draw.
+func (s *mctsStrategy) playout(board domain.GameBoard, char domain.GameBoardChar) domain.GameBoardChar {
+	f := freeCells(board)
+	for {
+		w := board.Winner()
+		if w != domain.GameBoardCharNone || len(f) == 0 {
+			return w
+		}
+		k := s.r.Intn(len(f))
+		board.Set(f[k].i, f[k].j, char)
+		f[k] = f[len(f)-1]
+		f = f[:len(f)-1]
+		char = reverseChar(char)
+	}
+}
+
+type cell struct {
+	i, j int
+}
+
+// freeCells returns not used cells of the board.
+func freeCells(board domain.GameBoard) []cell {
+	var s []cell
+	for i := 0; i < board.Size(); i++ {
+		for j := 0; j < board.Size(); j++ {
+			if board.At(i, j) == domain.GameBoardCharNone {
+				s = append(s, cell{i, j})
+			}
+		}
+	}
+	return s
+}
+
+// reverseChar returns the rival's char.
+func reverseChar(char domain.GameBoardChar) domain.GameBoardChar {
 	if char != domain.GameBoardCharCross {
 		return domain.GameBoardCharCross
 	}
@@ -0,0 +1,35 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
+
+func TestMCTSStrategyIsDeterministic(t *testing.T) {
+	big, err := domain.NewGameBoardOfSize(5, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []struct {
+		name  string
+		board domain.GameBoard
+		char  domain.GameBoardChar
+	}{
+		{"Empty", domain.NewGameBoard(), domain.GameBoardCharCross},
+		{"Opening", domain.MustGameBoardFromString("----X----"), domain.GameBoardCharNought},
+		{"Big", big, domain.GameBoardCharCross},
+	} {
+		t.Run(i.name, func(t *testing.T) {
+			var m [2][2]int
+			for n := range m {
+				s := NewMCTSStrategy(2000, 0, rand.New(rand.NewSource(1)))
+				m[n][0], m[n][1] = s.BestMove(i.board, i.char)
+			}
+			if m[0] != m[1] {
+				t.Fatalf("BestMove() = %v, then %v, want the same move", m[0], m[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package game
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Sweeper periodically marks the running games having no move for the idle period
+// as abandoned.
+type Sweeper struct {
+	s        *Service
+	idle     time.Duration
+	interval time.Duration
+}
+
+func NewSweeper(service *Service, idle, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		s:        service,
+		idle:     idle,
+		interval: interval,
+	}
+}
+
+// Run sweeps the games every interval until the context is done. A sweep in progress
+// is cancelled along with the context.
+func (s *Sweeper) Run(ctx context.Context) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		s.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Sweeper) sweep(ctx context.Context) {
+	n, err := s.s.Abandon(ctx, s.idle)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		log.Printf("Abandoned %d games\n", n)
+	}
+}
@@ -0,0 +1,74 @@
+package game
+
+//go:generate go run ./tablegen -out table.bin
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"sync"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/app/module/game/table"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
+
+// tableBin is the perfect play table of the classic 3x3 board, see the table package.
+//
+//go:embed table.bin
+var tableBin []byte
+
+var (
+	tableOnce sync.Once
+	tableMap  map[uint16]uint16
+)
+
+type tableStrategy struct {
+	r Rand
+	f Strategy
+}
+
+// NewTableStrategy creates a new Strategy playing perfectly on the classic 3x3 board by
+// looking the position up in the precomputed table. Positions the table doesn't cover
+// are delegated to the MiniMax Strategy. One of equally good moves is chosen randomly.
+func NewTableStrategy(rand Rand) Strategy {
+	return &tableStrategy{
+		r: rand,
+		f: NewMinimaxStrategy(rand),
+	}
+}
+
+func (s *tableStrategy) BestMove(board domain.GameBoard, char domain.GameBoardChar) (int, int) {
+	m := tableMoves(board, char)
+	if len(m) == 0 {
+		return s.f.BestMove(board, char)
+	}
+	// Break ties randomly.
+	i := m[s.r.Intn(len(m))]
+	return i.i, i.j
+}
+
+// tableMoves looks up the optimal moves in the table. It returns nil if the position
+// is not covered by the table.
+func tableMoves(board domain.GameBoard, char domain.GameBoardChar) []cell {
+	if board.Size() != 3 || board.Win() != 3 {
+		return nil
+	}
+	tableOnce.Do(func() {
+		tableMap = make(map[uint16]uint16, len(tableBin)/4)
+		for n := 0; n+4 <= len(tableBin); n += 4 {
+			tableMap[binary.LittleEndian.Uint16(tableBin[n:])] = binary.LittleEndian.Uint16(tableBin[n+2:])
+		}
+	})
+	c, k := table.Canonical(board)
+	v, ok := tableMap[table.Key(c, char)]
+	if !ok {
+		return nil
+	}
+	// Map the canonical cells back to the board.
+	var s []cell
+	for n := 0; n < 9; n++ {
+		if v&(1<<table.Symmetries[k][n]) != 0 {
+			s = append(s, cell{n / 3, n % 3})
+		}
+	}
+	return s
+}
@@ -0,0 +1,206 @@
+// Package table implements the perfect play table of the classic 3x3 board. The table
+// consists of little-endian uint16 pairs sorted by the first one. The first one is the
+// position key, see Key, the second one is the bitmask of the optimal moves cells in
+// the canonical position. The package doesn't depend on the game module embedding the
+// table, so that the table can be generated without it.
+package table
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
+
+// Symmetries are the 8 symmetries of the 3x3 board. The symmetry moves the cell n into
+// the cell s[n].
+var Symmetries = func() [8][9]int {
+	var s [8][9]int
+	for n := 0; n < 9; n++ {
+		i, j := n/3, n%3
+		for k, c := range [8][2]int{
+			{i, j},         // Identity
+			{j, 2 - i},     // Rotation by 90
+			{2 - i, 2 - j}, // Rotation by 180
+			{2 - j, i},     // Rotation by 270
+			{i, 2 - j},     // Horizontal reflection
+			{2 - i, j},     // Vertical reflection
+			{j, i},         // Main diagonal reflection
+			{2 - j, 2 - i}, // Anti-diagonal reflection
+		} {
+			s[k][n] = c[0]*3 + c[1]
+		}
+	}
+	return s
+}()
+
+// Canonical returns the canonical code of the position and the symmetry transforming
+// the board into the canonical position. The code is the board as a base-3 number
+// where the cell 0 is the most significant digit.
+func Canonical(board domain.GameBoard) (uint16, int) {
+	var (
+		b uint16
+		k int
+	)
+	for n, s := range Symmetries {
+		var t [9]domain.GameBoardChar
+		for i := 0; i < 9; i++ {
+			t[s[i]] = board.At(i/3, i%3)
+		}
+		var c uint16
+		for _, i := range t {
+			c *= 3
+			switch i {
+			case domain.GameBoardCharCross:
+				c += 1
+			case domain.GameBoardCharNought:
+				c += 2
+			}
+		}
+		if n == 0 || c < b {
+			b, k = c, n
+		}
+	}
+	return b, k
+}
+
+// Key returns the table key of the canonical position for the side to move.
+func Key(code uint16, char domain.GameBoardChar) uint16 {
+	if char == domain.GameBoardCharCross {
+		return code * 2
+	}
+	return code*2 + 1
+}
+
+// Board decodes the canonical code into the board.
+func Board(code uint16) domain.GameBoard {
+	b := domain.NewGameBoard()
+	for n := 8; n >= 0; n-- {
+		switch code % 3 {
+		case 1:
+			b.Set(n/3, n%3, domain.GameBoardCharCross)
+		case 2:
+			b.Set(n/3, n%3, domain.GameBoardCharNought)
+		}
+		code /= 3
+	}
+	return b
+}
+
+// Write enumerates every position reachable on the classic 3x3 board, whichever side
+// moves first, and writes the table to w.
+func Write(w io.Writer) error {
+	m := make(map[uint16]uint16)
+	s := &solver{m: make(map[uint16]int)}
+
+	var walk func(board domain.GameBoard, char domain.GameBoardChar)
+	walk = func(board domain.GameBoard, char domain.GameBoardChar) {
+		if board.Winner() != domain.GameBoardCharNone || board.IsFull() {
+			return
+		}
+		c, _ := Canonical(board)
+		k := Key(c, char)
+		if _, ok := m[k]; ok {
+			return
+		}
+		m[k] = s.optimal(Board(c), char)
+
+		for n := 0; n < 9; n++ {
+			if board.At(n/3, n%3) == domain.GameBoardCharNone {
+				b := board.Clone()
+				b.Set(n/3, n%3, char)
+				walk(b, rival(char))
+			}
+		}
+	}
+	walk(domain.NewGameBoard(), domain.GameBoardCharCross)
+	walk(domain.NewGameBoard(), domain.GameBoardCharNought)
+
+	k := make([]uint16, 0, len(m))
+	for i := range m {
+		k = append(k, i)
+	}
+	sort.Slice(k, func(i, j int) bool {
+		return k[i] < k[j]
+	})
+	for _, i := range k {
+		err := binary.Write(w, binary.LittleEndian, [2]uint16{i, m[i]})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// solver rates the positions by the complete search. The rating of a won position is
+// the number of free cells plus one for the winner, so that quicker wins and slower
+// losses are preferred, and 0 for a draw, the same as the MiniMax Strategy does.
+type solver struct {
+	// m caches the ratings by the position keys.
+	m map[uint16]int
+}
+
+// optimal returns the bitmask of the cells of the equally best moves.
+func (s *solver) optimal(board domain.GameBoard, char domain.GameBoardChar) uint16 {
+	var (
+		v uint16
+		b int
+	)
+	for n := 0; n < 9; n++ {
+		if board.At(n/3, n%3) != domain.GameBoardCharNone {
+			continue
+		}
+		board.Set(n/3, n%3, char)
+		r := -s.rate(board, rival(char))
+		board.Set(n/3, n%3, domain.GameBoardCharNone)
+		if v == 0 || r > b {
+			v, b = 0, r
+		}
+		if r == b {
+			v |= 1 << n
+		}
+	}
+	return v
+}
+
+// rate returns the rating of the position for the side to move.
+func (s *solver) rate(board domain.GameBoard, char domain.GameBoardChar) int {
+	if w := board.Winner(); w != domain.GameBoardCharNone {
+		r := 9 - board.Moves() + 1
+		if w == char {
+			return r
+		}
+		return -r
+	}
+	if board.IsFull() {
+		return 0
+	}
+	c, _ := Canonical(board)
+	k := Key(c, char)
+	if r, ok := s.m[k]; ok {
+		return r
+	}
+	r := 0
+	for n, ok := 0, false; n < 9; n++ {
+		if board.At(n/3, n%3) != domain.GameBoardCharNone {
+			continue
+		}
+		board.Set(n/3, n%3, char)
+		v := -s.rate(board, rival(char))
+		board.Set(n/3, n%3, domain.GameBoardCharNone)
+		if !ok || v > r {
+			r, ok = v, true
+		}
+	}
+	s.m[k] = r
+	return r
+}
+
+// rival returns the rival's char.
+func rival(char domain.GameBoardChar) domain.GameBoardChar {
+	if char != domain.GameBoardCharCross {
+		return domain.GameBoardCharCross
+	}
+	return domain.GameBoardCharNought
+}
@@ -0,0 +1,57 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/app/module/game/table"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
+
+func TestTableIsGenerated(t *testing.T) {
+	var b bytes.Buffer
+	err := table.Write(&b)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !bytes.Equal(b.Bytes(), tableBin) {
+		t.Fatalf("table.bin differs from the generated table, run go generate")
+	}
+}
+
+func TestTableMatchesMinimax(t *testing.T) {
+	seen := make(map[string]bool)
+
+	var walk func(board domain.GameBoard, char domain.GameBoardChar)
+	walk = func(board domain.GameBoard, char domain.GameBoardChar) {
+		if board.Winner() != domain.GameBoardCharNone || board.IsFull() {
+			return
+		}
+		k := board.String() + string(char)
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+
+		want := make(map[cell]bool)
+		for _, i := range OptimalMoves(board, char) {
+			want[cell{i[0], i[1]}] = true
+		}
+		got := tableMoves(board, char)
+		ok := len(got) == len(want)
+		for _, i := range got {
+			ok = ok && want[i]
+		}
+		if !ok {
+			t.Fatalf("position %s with %s to move: got %v optimal moves, want %v", board.String(), char, got, want)
+		}
+
+		for _, i := range freeCells(board) {
+			b := board.Clone()
+			b.Set(i.i, i.j, char)
+			walk(b, reverseChar(char))
+		}
+	}
+	walk(domain.NewGameBoard(), domain.GameBoardCharCross)
+	walk(domain.NewGameBoard(), domain.GameBoardCharNought)
+}
@@ -0,0 +1,40 @@
+// Command tablegen generates the perfect play table of the classic 3x3 board embedded
+// into the game module. The table is checked against the MiniMax Strategy by the game
+// module tests.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/app/module/game/table"
+)
+
+func main() {
+	var out string
+	flag.StringVar(&out, "out", "", "Path of the table file to generate")
+	flag.Parse()
+
+	if out == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		log.Fatalf("unable to create table file: %v\n", err)
+	}
+	w := bufio.NewWriter(f)
+	err = table.Write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		log.Fatalf("unable to write table: %v\n", err)
+	}
+}
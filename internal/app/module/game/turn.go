@@ -0,0 +1,92 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+)
+
+// turn is the turn resolution state machine. It applies half-moves to the game one by
+// one and resolves the game status after every one of them, so that no move can be
+// made once the game is over.
+type turn struct {
+	game  *domain.Game
+	moves domain.GameMoves
+}
+
+func newTurn(game *domain.Game) *turn {
+	return &turn{
+		game: game,
+	}
+}
+
+// over checks if the game is in a terminal state.
+func (t *turn) over() bool {
+	return t.game.Status != domain.GameStatusRunning
+}
+
+// next returns the side to move.
+func (t *turn) next() domain.GamePlayer {
+	switch t.game.LastMover {
+	case "":
+		return t.game.FirstMover
+	case domain.GamePlayerHuman:
+		return domain.GamePlayerComputer
+	}
+	return domain.GamePlayerHuman
+}
+
+// apply makes a half-move of the player into the cell at row i and column j.
+func (t *turn) apply(player domain.GamePlayer, i, j int) error {
+	if t.over() {
+		return errorx.WrapInBadRequest(fmt.Errorf("game is already over"))
+	}
+	if !t.game.Board.Contains(i, j) {
+		return errorx.WrapInBadRequest(fmt.Errorf("invalid cell"))
+	}
+	if t.game.Board.At(i, j) != domain.GameBoardCharNone {
+		return errorx.WrapInBadRequest(fmt.Errorf("cell is already used"))
+	}
+
+	c := t.game.Char
+	if player == domain.GamePlayerHuman {
+		c = t.game.PlayerChar()
+	}
+
+	// Apply the move on a copy to keep the previous board untouched.
+	m := &domain.GameMove{
+		Ply:    t.game.Board.Moves() + 1,
+		Player: player,
+		Cell:   t.game.Board.Index(i, j),
+		Char:   c,
+		Time:   time.Now(),
+	}
+	t.game.Board = t.game.Board.Clone()
+	t.game.Board.Set(i, j, c)
+	t.game.LastMover = player
+	t.moves = append(t.moves, m)
+
+	t.resolve(m)
+	return nil
+}
+
+// resolve updates the game status after the move.
+func (t *turn) resolve(move *domain.GameMove) {
+	l := t.game.Board.WinningLine()
+	if l == nil {
+		if t.game.Board.IsFull() {
+			t.game.Status = domain.GameStatusDraw
+		}
+		return
+	}
+	if move.Char == domain.GameBoardCharCross {
+		t.game.Status = domain.GameStatusCrossWon
+	} else {
+		t.game.Status = domain.GameStatusNoughtWon
+	}
+	t.game.WinningMove = &move.Cell
+	t.game.WinningLine = l
+	t.game.Winner = move.Char
+}
@@ -0,0 +1,78 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo"
+)
+
+// MaxKeyLength is the maximum length of the idempotency key.
+const MaxKeyLength = 255
+
+type Service struct {
+	r   repo.IdempotencyRepository
+	ttl time.Duration
+}
+
+// NewService creates a new Service keeping the responses for the ttl.
+func NewService(repo repo.IdempotencyRepository, ttl time.Duration) *Service {
+	return &Service{
+		r:   repo,
+		ttl: ttl,
+	}
+}
+
+// Begin begins the request made with the key. The fingerprint identifies the request.
+// It returns the response to replay if the request has been made already or nil if
+// the request is to be made and either finished or aborted then. It returns
+// errorx.BadRequest if the key is reused with another request and errorx.Conflict if
+// the request with the key is in progress.
+func (s *Service) Begin(ctx context.Context, key, fingerprint string) (*domain.IdempotentResponse, error) {
+	if key == "" || len(key) > MaxKeyLength {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("invalid idempotency key"))
+	}
+	v, err := s.r.Reserve(ctx, &domain.IdempotencyKey{
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	})
+	if err != nil {
+		log.Printf("Unable to reserve idempotency key: %v\n", err)
+		return nil, err
+	}
+	if v == nil {
+		return nil, nil
+	}
+	if v.Fingerprint != fingerprint {
+		return nil, errorx.WrapInBadRequest(fmt.Errorf("idempotency key is reused with another request"))
+	}
+	if v.Response == nil {
+		return nil, errorx.WrapInConflict(fmt.Errorf("request with the idempotency key is in progress"))
+	}
+	return v.Response, nil
+}
+
+// Finish keeps the response of the request made with the key.
+func (s *Service) Finish(ctx context.Context, key string, response *domain.IdempotentResponse) error {
+	err := s.r.Complete(ctx, key, response)
+	if err != nil {
+		log.Printf("Unable to complete idempotency key: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// Abort forgets the request made with the key, so that it can be repeated.
+func (s *Service) Abort(ctx context.Context, key string) error {
+	err := s.r.Release(ctx, key)
+	if err != nil {
+		log.Printf("Unable to release idempotency key: %v\n", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package errorx
+
+import "errors"
+
+type Conflict struct {
+	err error
+}
+
+// NewConflict returns new Conflict instance.
+func NewConflict() *Conflict {
+	return &Conflict{}
+}
+
+// WrapInConflict wraps err into Conflict.
+func WrapInConflict(err error) *Conflict {
+	return &Conflict{
+		err: err,
+	}
+}
+
+// IsConflict checks if err is instance of Conflict.
+func IsConflict(err error) bool {
+	var e *Conflict
+	return errors.As(err, &e)
+}
+
+func (e *Conflict) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return "conflict"
+}
+
+// Unwrap unwraps error. See errors.Unwrap for more details.
+func (e *Conflict) Unwrap() error {
+	return e.err
+}
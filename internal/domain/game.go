@@ -1,7 +1,10 @@
 package domain
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -11,10 +14,89 @@ type Games []*Game
 
 // Game represents a game.
 type Game struct {
-	Id     GameId
-	Board  GameBoard
+	Id    GameId
+	Board GameBoard
+	// Start is the position the Game has started from. Moves are made on it.
+	Start  GameBoard
 	Status GameStatus
-	Char   GameBoardChar
+	// Char is the computer's GameBoardChar.
+	Char GameBoardChar
+	// FirstMover is the side made the first move.
+	FirstMover GamePlayer
+	// LastMover is the side made the last move. If the Game is over, it is the side
+	// whose move has ended the Game.
+	LastMover GamePlayer
+	// WinningMove is the index of the cell of the move completed the winning line.
+	// It is nil unless the Game is won.
+	WinningMove *int
+	// WinningLine is the line of the winner's chars. It is nil unless the Game is won.
+	WinningLine *GameBoardLine
+	// Winner is the winner's GameBoardChar. It is empty unless the Game is won or resigned.
+	Winner GameBoardChar
+	// Strategy is the strategy the computer plays with.
+	Strategy GameStrategy
+	// Hints is the number of hints the user has asked for.
+	Hints int
+	// UndoLimit is the number of times the user can take back a move.
+	UndoLimit int
+	// Undos is the number of moves the user has taken back.
+	Undos int
+	// Created is the time the Game has started.
+	Created time.Time
+	// Parent is the Game this Game has been forked from. It is nil unless forked.
+	Parent *GameParent
+	// Version is incremented on every update of the Game. It detects concurrent updates.
+	Version int
+}
+
+// PlayerChar returns the user's GameBoardChar.
+func (g *Game) PlayerChar() GameBoardChar {
+	if g.Char == GameBoardCharCross {
+		return GameBoardCharNought
+	}
+	return GameBoardCharCross
+}
+
+// ToMove returns the side to move, it is empty unless the Game is running.
+func (g *Game) ToMove() GamePlayer {
+	switch {
+	case g.Status != GameStatusRunning:
+		return ""
+	case g.LastMover == "":
+		return g.FirstMover
+	case g.LastMover == GamePlayerHuman:
+		return GamePlayerComputer
+	}
+	return GamePlayerHuman
+}
+
+// Clone returns a deep copy of the Game.
+func (g *Game) Clone() *Game {
+	v := *g
+	v.Board = g.Board.Clone()
+	v.Start = g.Start.Clone()
+	if g.WinningMove != nil {
+		n := *g.WinningMove
+		v.WinningMove = &n
+	}
+	if g.WinningLine != nil {
+		v.WinningLine = &GameBoardLine{
+			Kind:  g.WinningLine.Kind,
+			Cells: append([]int(nil), g.WinningLine.Cells...),
+		}
+	}
+	if g.Parent != nil {
+		p := *g.Parent
+		v.Parent = &p
+	}
+	return &v
+}
+
+// GameParent represents the position of the Game another Game has been forked from.
+type GameParent struct {
+	Id GameId
+	// Ply is the ply of the last move made before forking.
+	Ply int
 }
 
 // GameId represents Game identifier.
@@ -45,43 +127,117 @@ func MustGameIdFromString(s string) GameId {
 	return id
 }
 
-// GameBoard represents Game board.
-type GameBoard [3][3]GameBoardChar
+// GameBoard represents Game board. The board is a square grid of GameBoardChar,
+// a player wins by placing Win chars in a row horizontally, vertically or diagonally.
+type GameBoard struct {
+	size  int
+	win   int
+	cells []GameBoardChar
+}
+
+const (
+	// GameBoardDefaultSize is the size of the classic GameBoard.
+	GameBoardDefaultSize = 3
+	// GameBoardMinSize is the minimal supported GameBoard size.
+	GameBoardMinSize = 3
+	// GameBoardMaxSize is the maximal supported GameBoard size.
+	GameBoardMaxSize = 10
+	// GameBoardMinWin is the minimal supported number of chars in a row to win.
+	GameBoardMinWin = 3
+)
 
-// NewGameBoard creates a new blank GameBoard.
+// NewGameBoard creates a new blank classic 3x3 GameBoard.
 func NewGameBoard() GameBoard {
-	return GameBoard{
-		{GameBoardCharNone, GameBoardCharNone, GameBoardCharNone},
-		{GameBoardCharNone, GameBoardCharNone, GameBoardCharNone},
-		{GameBoardCharNone, GameBoardCharNone, GameBoardCharNone},
+	return MustNewGameBoardOfSize(GameBoardDefaultSize, GameBoardDefaultSize)
+}
+
+// NewGameBoardOfSize creates a new blank GameBoard of size x size cells where win
+// chars in a row are required to win. It returns an error if size is not within
+// GameBoardMinSize and GameBoardMaxSize or win is not within GameBoardMinWin and size.
+func NewGameBoardOfSize(size, win int) (GameBoard, error) {
+	if size < GameBoardMinSize || size > GameBoardMaxSize {
+		return GameBoard{}, fmt.Errorf("invalid domain.GameBoard size: %d", size)
+	}
+	if win < GameBoardMinWin || win > size {
+		return GameBoard{}, fmt.Errorf("invalid domain.GameBoard win length: %d", win)
+	}
+	b := GameBoard{
+		size:  size,
+		win:   win,
+		cells: make([]GameBoardChar, size*size),
 	}
+	for n := range b.cells {
+		b.cells[n] = GameBoardCharNone
+	}
+	return b, nil
 }
 
-// GameBoardFromString creates a new GameBoard from string. It returns an error if
-// the string doesn't contain 9 chars or contains chars other than GameBoardCharCross,
-// GameBoardCharNought and GameBoardCharNone.
+// MustNewGameBoardOfSize wraps NewGameBoardOfSize. It panics if
+// NewGameBoardOfSize returns an error.
+func MustNewGameBoardOfSize(size, win int) GameBoard {
+	b, err := NewGameBoardOfSize(size, win)
+	if err != nil {
+		panic(fmt.Sprintf("MustNewGameBoardOfSize: %v", err))
+	}
+	return b
+}
+
+// GameBoardFromString creates a new GameBoard from string. The board size is derived
+// from the string length and the number of chars in a row to win equals to the size.
+// Rows may be separated by "/", e.g. "XO-/-X-/-OX", and the letters O, x and o are
+// accepted as aliases of GameBoardCharNought and GameBoardCharCross. It returns an
+// error if the string length is not a square of supported size or the string contains
+// chars other than GameBoardCharCross, GameBoardCharNought and GameBoardCharNone.
 func GameBoardFromString(s string) (GameBoard, error) {
-	var b GameBoard
-	if len(s) != 9 {
-		return b, fmt.Errorf("invalid domain.GameBoard")
+	s, err := normalizeGameBoardString(s)
+	if err != nil {
+		return GameBoard{}, err
 	}
-	n := 0
-	for i := 0; i < 3; i++ {
-		for j := 0; j < 3; j++ {
-			c := GameBoardChar(s[n])
-			n++
-			switch c {
-			case GameBoardCharNone, GameBoardCharCross, GameBoardCharNought:
-				// OK
-			default:
-				return b, fmt.Errorf("invalid domain.GameBoard")
-			}
-			b[i][j] = c
+	return GameBoardFromStringWithWin(s, sizeFromLen(len(s)))
+}
+
+// GameBoardFromStringWithWin works like GameBoardFromString but sets the number of
+// chars in a row to win.
+func GameBoardFromStringWithWin(s string, win int) (GameBoard, error) {
+	s, err := normalizeGameBoardString(s)
+	if err != nil {
+		return GameBoard{}, err
+	}
+	size := sizeFromLen(len(s))
+	if size*size != len(s) {
+		return GameBoard{}, fmt.Errorf("invalid domain.GameBoard")
+	}
+	b, err := NewGameBoardOfSize(size, win)
+	if err != nil {
+		return GameBoard{}, err
+	}
+	for n := range b.cells {
+		c := GameBoardChar(s[n])
+		switch c {
+		case GameBoardCharNone, GameBoardCharCross, GameBoardCharNought:
+			// OK
+		default:
+			return GameBoard{}, fmt.Errorf("invalid domain.GameBoard")
 		}
+		b.cells[n] = c
 	}
 	return b, nil
 }
 
+// normalizeGameBoardString converts the alternative notations into the plain one.
+func normalizeGameBoardString(s string) (string, error) {
+	if strings.Contains(s, "/") {
+		r := strings.Split(s, "/")
+		for _, i := range r {
+			if len(i) != len(r) {
+				return "", fmt.Errorf("invalid domain.GameBoard: rows must have %d cells", len(r))
+			}
+		}
+		s = strings.Join(r, "")
+	}
+	return gameBoardCharAliases.Replace(s), nil
+}
+
 // MustGameBoardFromString wraps GameBoardFromString. It panics if
 // GameBoardFromString returns an error.
 func MustGameBoardFromString(s string) GameBoard {
@@ -92,34 +248,103 @@ func MustGameBoardFromString(s string) GameBoard {
 	return b
 }
 
+// MustGameBoardFromStringWithWin wraps GameBoardFromStringWithWin. It panics if
+// GameBoardFromStringWithWin returns an error.
+func MustGameBoardFromStringWithWin(s string, win int) GameBoard {
+	b, err := GameBoardFromStringWithWin(s, win)
+	if err != nil {
+		panic(fmt.Sprintf("MustGameBoardFromStringWithWin: %v", err))
+	}
+	return b
+}
+
+func sizeFromLen(n int) int {
+	s := 0
+	for (s+1)*(s+1) <= n {
+		s++
+	}
+	return s
+}
+
+// Size returns the number of rows (and columns) of the GameBoard.
+func (a *GameBoard) Size() int {
+	return a.size
+}
+
+// Win returns the number of chars in a row required to win.
+func (a *GameBoard) Win() int {
+	return a.win
+}
+
+// At returns GameBoardChar placed in the cell at row i and column j.
+func (a *GameBoard) At(i, j int) GameBoardChar {
+	return a.cells[i*a.size+j]
+}
+
+// Set places GameBoardChar into the cell at row i and column j.
+func (a *GameBoard) Set(i, j int, c GameBoardChar) {
+	a.cells[i*a.size+j] = c
+}
+
+// Contains checks if row i and column j are within the GameBoard.
+func (a *GameBoard) Contains(i, j int) bool {
+	return i >= 0 && i < a.size && j >= 0 && j < a.size
+}
+
+// Index returns the index of the cell at row i and column j. Cells are numbered
+// row by row starting from 0.
+func (a *GameBoard) Index(i, j int) int {
+	return i*a.size + j
+}
+
+// Cell returns the row and the column of the cell with index n.
+func (a *GameBoard) Cell(n int) (int, int) {
+	return n / a.size, n % a.size
+}
+
+// Moves returns the number of moves made on the GameBoard.
+func (a *GameBoard) Moves() int {
+	n := 0
+	for _, c := range a.cells {
+		if c != GameBoardCharNone {
+			n++
+		}
+	}
+	return n
+}
+
+// Clone returns a deep copy of the GameBoard.
+func (a *GameBoard) Clone() GameBoard {
+	b := *a
+	b.cells = make([]GameBoardChar, len(a.cells))
+	copy(b.cells, a.cells)
+	return b
+}
+
 func (a *GameBoard) String() string {
 	var s string
-	for _, i := range a {
-		for _, j := range i {
-			s += string(j)
-		}
+	for _, c := range a.cells {
+		s += string(c)
 	}
 	return s
 }
 
 // IsFull check if the GameBoard is full.
 func (a *GameBoard) IsFull() bool {
-	for i := 0; i < 3; i++ {
-		for j := 0; j < 3; j++ {
-			if a[i][j] == GameBoardCharNone {
-				return false
-			}
+	for _, c := range a.cells {
+		if c == GameBoardCharNone {
+			return false
 		}
 	}
 	return true
 }
 
-// Diff provides different positions.
+// Diff provides different positions. Boards of different sizes differ in all positions.
 func (a *GameBoard) Diff(board GameBoard) [][2]int {
 	var s [][2]int
-	for i := 0; i < 3; i++ {
-		for j := 0; j < 3; j++ {
-			if a[i][j] != board[i][j] {
+	for i := 0; i < a.size; i++ {
+		for j := 0; j < a.size; j++ {
+			if board.size != a.size || a.At(i, j) != board.At(i, j) {
 				s = append(s, [2]int{i, j})
 			}
 		}
@@ -130,35 +355,188 @@ func (a *GameBoard) Diff(board GameBoard) [][2]int {
 // Winner checks if there is a winner and returns winner's GameBoardChar.
 // It returns GameBoardCharNone if there is no winner yet.
 func (a *GameBoard) Winner() GameBoardChar {
-	// Check rows
-	for i := 0; i < 3; i++ {
-		c := a[i][0]
-		if c != GameBoardCharNone && c == a[i][1] && c == a[i][2] {
-			return c
+	l := a.WinningLine()
+	if l == nil {
+		return GameBoardCharNone
+	}
+	i, j := a.Cell(l.Cells[0])
+	return a.At(i, j)
+}
+
+// WinningLine checks if there is a winner and returns the winning GameBoardLine.
+// It returns nil if there is no winner yet.
+func (a *GameBoard) WinningLine() *GameBoardLine {
+	l := a.winningLines(true)
+	if len(l) == 0 {
+		return nil
+	}
+	return l[0]
+}
+
+// WinningLines returns all the GameBoardLine of Win equal chars in a row.
+func (a *GameBoard) WinningLines() []*GameBoardLine {
+	return a.winningLines(false)
+}
+
+func (a *GameBoard) winningLines(first bool) []*GameBoardLine {
+	var s []*GameBoardLine
+	// Check rows, cols, left-right and right-left diagonals starting at every cell.
+	for _, k := range []GameBoardLineKind{
+		GameBoardLineKindRow,
+		GameBoardLineKindColumn,
+		GameBoardLineKindDiagonal,
+		GameBoardLineKindAntiDiagonal,
+	} {
+		di, dj := k.direction()
+		for i := 0; i < a.size; i++ {
+			for j := 0; j < a.size; j++ {
+				if a.line(i, j, di, dj) != GameBoardCharNone {
+					l := &GameBoardLine{
+						Kind:  k,
+						Cells: make([]int, a.win),
+					}
+					for n := range l.Cells {
+						l.Cells[n] = a.Index(i+di*n, j+dj*n)
+					}
+					s = append(s, l)
+					if first {
+						return s
+					}
+				}
+			}
 		}
 	}
+	return s
+}
 
-	// Check cols
-	for i := 0; i < 3; i++ {
-		c := a[0][i]
-		if c != GameBoardCharNone && c == a[1][i] && c == a[2][i] {
-			return c
+var (
+	// ErrGameBoardPieceCount means that one side has made more than one move more than the other.
+	ErrGameBoardPieceCount = errors.New("one side has more than one extra piece")
+	// ErrGameBoardBothWon means that both sides have completed a line.
+	ErrGameBoardBothWon = errors.New("both sides have won")
+	// ErrGameBoardDisjointLines means that the winning lines can't be completed by a single move.
+	ErrGameBoardDisjointLines = errors.New("winning lines can't be completed by a single move")
+	// ErrGameBoardWinnerNotLast means that the winner is not the side moved last.
+	ErrGameBoardWinnerNotLast = errors.New("winner hasn't made the last move")
+	// ErrGameBoardWrongTurn means that the side to move has made more moves than the other.
+	ErrGameBoardWrongTurn = errors.New("side to move has already made more moves")
+)
+
+// Validate checks that the GameBoard position can be reached in a real game: the numbers
+// of pieces differ by at most one, at most one side has won with a single move, and the
+// winner has made the last move. The toMove is the char of the side to move, it is not
+// checked if GameBoardCharNone.
+func (a *GameBoard) Validate(toMove GameBoardChar) error {
+	x, o := 0, 0
+	for _, c := range a.cells {
+		switch c {
+		case GameBoardCharCross:
+			x++
+		case GameBoardCharNought:
+			o++
 		}
 	}
+	if x-o > 1 || o-x > 1 {
+		return ErrGameBoardPieceCount
+	}
 
-	// Check left-right diagonal
-	c00 := a[0][0]
-	if c00 != GameBoardCharNone && c00 == a[1][1] && c00 == a[2][2] {
-		return c00
+	// The side having more pieces has moved last, either side could if equal.
+	last := map[GameBoardChar]bool{
+		GameBoardCharCross:  x >= o,
+		GameBoardCharNought: o >= x,
 	}
+	if toMove != GameBoardCharNone {
+		if last[toMove] && x != o {
+			return ErrGameBoardWrongTurn
+		}
+		last[toMove] = false
+	}
+
+	l := a.WinningLines()
+	if len(l) == 0 {
+		return nil
+	}
+	i, j := a.Cell(l[0].Cells[0])
+	w := a.At(i, j)
 
-	// Check right-left diagonal
-	c02 := a[0][2]
-	if c02 != GameBoardCharNone && c02 == a[1][1] && c02 == a[2][0] {
-		return c02
+	// The last move must belong to every winning line.
+	m := make(map[int]int)
+	for _, n := range l {
+		i, j = a.Cell(n.Cells[0])
+		if a.At(i, j) != w {
+			return ErrGameBoardBothWon
+		}
+		for _, c := range n.Cells {
+			m[c]++
+		}
+	}
+	ok := false
+	for _, c := range m {
+		if c == len(l) {
+			ok = true
+		}
+	}
+	if !ok {
+		return ErrGameBoardDisjointLines
 	}
 
-	return GameBoardCharNone
+	if !last[w] {
+		return ErrGameBoardWinnerNotLast
+	}
+	return nil
+}
+
+// line checks if Win equal chars are placed starting at row i and column j in the
+// direction di, dj. It returns the char or GameBoardCharNone otherwise.
+func (a *GameBoard) line(i, j, di, dj int) GameBoardChar {
+	ei, ej := i+di*(a.win-1), j+dj*(a.win-1)
+	if !a.Contains(ei, ej) {
+		return GameBoardCharNone
+	}
+	c := a.At(i, j)
+	if c == GameBoardCharNone {
+		return GameBoardCharNone
+	}
+	for n := 1; n < a.win; n++ {
+		if a.At(i+di*n, j+dj*n) != c {
+			return GameBoardCharNone
+		}
+	}
+	return c
+}
+
+// GameBoardLine represents a line of cells on GameBoard.
+type GameBoardLine struct {
+	Kind GameBoardLineKind
+	// Cells are indexes of the line cells.
+	Cells []int
+}
+
+// GameBoardLineKind represents GameBoardLine direction.
+type GameBoardLineKind string
+
+const (
+	// GameBoardLineKindRow means that the line is horizontal.
+	GameBoardLineKindRow GameBoardLineKind = "ROW"
+	// GameBoardLineKindColumn means that the line is vertical.
+	GameBoardLineKindColumn GameBoardLineKind = "COLUMN"
+	// GameBoardLineKindDiagonal means that the line goes from the top-left to the bottom-right.
+	GameBoardLineKindDiagonal GameBoardLineKind = "DIAGONAL"
+	// GameBoardLineKindAntiDiagonal means that the line goes from the top-right to the bottom-left.
+	GameBoardLineKindAntiDiagonal GameBoardLineKind = "ANTI_DIAGONAL"
+)
+
+func (k GameBoardLineKind) direction() (int, int) {
+	switch k {
+	case GameBoardLineKindRow:
+		return 0, 1
+	case GameBoardLineKindColumn:
+		return 1, 0
+	case GameBoardLineKindDiagonal:
+		return 1, 1
+	default:
+		return 1, -1
+	}
 }
 
 type GameBoardChar string
@@ -169,6 +547,24 @@ const (
 	GameBoardCharNought GameBoardChar = "0"
 )
 
+// gameBoardCharAliases replaces the letters accepted as the aliases of GameBoardChar,
+// the letters x for GameBoardCharCross and O, o for GameBoardCharNought.
+var gameBoardCharAliases = strings.NewReplacer(
+	"O", string(GameBoardCharNought),
+	"o", string(GameBoardCharNought),
+	"x", string(GameBoardCharCross),
+)
+
+// GameBoardCharFromString creates a player's GameBoardChar from string, the aliases
+// are accepted. It returns error if string is not a player's GameBoardChar.
+func GameBoardCharFromString(s string) (GameBoardChar, error) {
+	c := GameBoardChar(gameBoardCharAliases.Replace(s))
+	if c != GameBoardCharCross && c != GameBoardCharNought {
+		return "", fmt.Errorf("invalid domain.GameBoardChar: %q", s)
+	}
+	return c, nil
+}
+
 // GameStatus represents Game status.
 type GameStatus string
 
@@ -181,4 +577,52 @@ const (
 	GameStatusNoughtWon GameStatus = "O_WON"
 	// GameStatusDraw means that the Game is over because the GameBoard is full.
 	GameStatusDraw GameStatus = "DRAW"
+	// GameStatusResigned means that the Game is over because the user has resigned.
+	GameStatusResigned GameStatus = "RESIGNED"
+	// GameStatusDrawAgreed means that the Game is over because the computer has accepted
+	// the user's draw offer.
+	GameStatusDrawAgreed GameStatus = "DRAW_AGREED"
+	// GameStatusAbandoned means that the Game is over because no move has been made for
+	// too long.
+	GameStatusAbandoned GameStatus = "ABANDONED"
+)
+
+// GameStrategy is a name of the strategy the computer plays a Game with.
+type GameStrategy string
+
+// GameMoves is a list of GameMove.
+type GameMoves []*GameMove
+
+// Clone returns a deep copy of the GameMoves.
+func (m GameMoves) Clone() GameMoves {
+	s := make(GameMoves, len(m))
+	for n, i := range m {
+		v := *i
+		s[n] = &v
+	}
+	return s
+}
+
+// GameMove represents a move made in a Game.
+type GameMove struct {
+	// Ply is the number of the move counting both sides' moves starting from 1.
+	Ply int
+	// Player is the side made the move.
+	Player GamePlayer
+	// Cell is the index of the cell the move was made into.
+	Cell int
+	// Char is the GameBoardChar placed by the move.
+	Char GameBoardChar
+	// Time is the time the move was made at.
+	Time time.Time
+}
+
+// GamePlayer represents a side playing a Game.
+type GamePlayer string
+
+const (
+	// GamePlayerHuman means that the move is made by the user.
+	GamePlayerHuman GamePlayer = "HUMAN"
+	// GamePlayerComputer means that the move is made by the engine.
+	GamePlayerComputer GamePlayer = "COMPUTER"
 )
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGameBoardValidate(t *testing.T) {
+	for _, i := range []struct {
+		name   string
+		board  string
+		win    int
+		toMove GameBoardChar
+		want   error
+	}{
+		{"Empty", "---------", 3, GameBoardCharNone, nil},
+		{"EmptyToMove", "---------", 3, GameBoardCharNought, nil},
+		{"Running", "X0-/-X-/---", 3, GameBoardCharNone, nil},
+		{"NoughtFirst", "0--------", 3, GameBoardCharCross, nil},
+		{"PieceCount", "XX-------", 3, GameBoardCharNone, ErrGameBoardPieceCount},
+		{"PieceCountNought", "00-/-0-/X--", 3, GameBoardCharNone, ErrGameBoardPieceCount},
+		{"BothWon", "XXX/000/---", 3, GameBoardCharNone, ErrGameBoardBothWon},
+		{"DisjointLines", "XXX0/0-0-/XXX0/0-0-", 3, GameBoardCharNone, ErrGameBoardDisjointLines},
+		{"CrossingLines", "XXX/X00/X00", 3, GameBoardCharNone, nil},
+		{"Won", "XXX/00-/---", 3, GameBoardCharNought, nil},
+		{"WinnerNotLast", "XXX/0-0/0-0", 3, GameBoardCharNone, ErrGameBoardWinnerNotLast},
+		{"WinnerToMove", "XXX/00-/0--", 3, GameBoardCharCross, ErrGameBoardWinnerNotLast},
+		{"WinnerEqualPieces", "XXX/00-/0--", 3, GameBoardCharNought, nil},
+		{"WrongTurn", "X--------", 3, GameBoardCharCross, ErrGameBoardWrongTurn},
+		{"WrongTurnNought", "0--------", 3, GameBoardCharNought, ErrGameBoardWrongTurn},
+		{"RightTurn", "X--------", 3, GameBoardCharNought, nil},
+	} {
+		t.Run(i.name, func(t *testing.T) {
+			b, err := GameBoardFromStringWithWin(i.board, i.win)
+			if err != nil {
+				t.Fatalf("GameBoardFromStringWithWin() error = %v", err)
+			}
+			err = b.Validate(i.toMove)
+			if !errors.Is(err, i.want) {
+				t.Fatalf("Validate() error = %v, want %v", err, i.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeGameBoardString(t *testing.T) {
+	for _, i := range []struct {
+		name string
+		s    string
+		want string
+		err  bool
+	}{
+		{"Plain", "X0-------", "X0-------", false},
+		{"Aliases", "xoO------", "X00------", false},
+		{"Rows", "XO-/-x-/o-X", "X0--X-0-X", false},
+		{"RowsOfBig", "X---/-0--/----/---x", "X----0---------X", false},
+		{"ShortRow", "X0-/-X/---", "", true},
+		{"MissingRow", "X0-/-X-", "", true},
+		{"TrailingSeparator", "X0-/-X-/---/", "", true},
+	} {
+		t.Run(i.name, func(t *testing.T) {
+			v, err := normalizeGameBoardString(i.s)
+			if (err != nil) != i.err {
+				t.Fatalf("normalizeGameBoardString() error = %v, want error %t", err, i.err)
+			}
+			if v != i.want {
+				t.Fatalf("normalizeGameBoardString() = %q, want %q", v, i.want)
+			}
+		})
+	}
+}
+
+func TestGameBoardFromString(t *testing.T) {
+	for _, i := range []struct {
+		name string
+		s    string
+		want string
+		err  bool
+	}{
+		{"Plain", "X0-------", "X0-------", false},
+		{"Aliases", "x-O/-X-/o--", "X-0-X-0--", false},
+		{"Big", "X---/-0--/----/---x", "X----0---------X", false},
+		{"InvalidChar", "X0-/-Y-/---", "", true},
+		{"InvalidLength", "X0-----", "", true},
+		{"InvalidRows", "X0/-X-/---", "", true},
+	} {
+		t.Run(i.name, func(t *testing.T) {
+			b, err := GameBoardFromString(i.s)
+			if (err != nil) != i.err {
+				t.Fatalf("GameBoardFromString() error = %v, want error %t", err, i.err)
+			}
+			if err == nil && b.String() != i.want {
+				t.Fatalf("GameBoardFromString() = %s, want %s", b.String(), i.want)
+			}
+		})
+	}
+}
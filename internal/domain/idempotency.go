@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// IdempotencyKey represents a request made with an idempotency key. Repeats of the request
+// made with the same key get the response of the first one.
+type IdempotencyKey struct {
+	Key string
+	// Fingerprint identifies the request made with the key. The key can't be reused with
+	// another request.
+	Fingerprint string
+	// Response is the response of the request. It is nil while the request is in progress.
+	Response  *IdempotentResponse
+	ExpiresAt time.Time
+}
+
+// IdempotentResponse represents the response replayed for repeats of the request.
+type IdempotentResponse struct {
+	Status int
+	Header map[string]string
+	Body   []byte
+}
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"time"
 
 	"github.com/mgrabazey/tic-tac-toe/internal/domain"
 )
@@ -15,12 +16,39 @@ type GameRepository interface {
 	// domain.Game couldn't be found.
 	Get(ctx context.Context, id domain.GameId) (*domain.Game, error)
 
-	// Create creates a new domain.Game.
-	Create(ctx context.Context, game *domain.Game) error
+	// Create creates a new domain.Game along with domain.GameMoves made so far. The
+	// domain.Game gets the first version. Returns errorx.Conflict if a domain.Game with
+	// the domain.GameId exists or has been deleted.
+	Create(ctx context.Context, game *domain.Game, moves domain.GameMoves) error
 
-	// Update updates the domain.Game. Returns errorx.NotFound error if the domain.Game
-	// couldn't be found.
-	Update(ctx context.Context, game *domain.Game) error
+	// CreateAll creates the domain.Game entities along with domain.GameMoves made so
+	// far atomically, the moves[n] are made in the games[n]. Either every domain.Game
+	// is created and gets the first version or none. Returns errorx.Conflict the same
+	// way as Create.
+	CreateAll(ctx context.Context, games domain.Games, moves []domain.GameMoves) error
+
+	// Update updates the domain.Game and appends new domain.GameMoves atomically.
+	// The domain.Game is updated only if its version is not changed since it was got,
+	// the version is incremented then. Returns errorx.NotFound error if the domain.Game
+	// couldn't be found and errorx.Conflict if the version has changed.
+	Update(ctx context.Context, game *domain.Game, moves domain.GameMoves) error
+
+	// Rewind updates the domain.Game and deletes domain.GameMoves made after the ply
+	// atomically. The version is checked and incremented the same way as by Update.
+	// Returns errorx.NotFound error if the domain.Game couldn't be found and
+	// errorx.Conflict if the version has changed.
+	Rewind(ctx context.Context, game *domain.Game, ply int) error
+
+	// Abandon marks the running domain.Game entities having no domain.GameMoves made or
+	// taken back since before as abandoned and increments their versions. The updates
+	// without domain.GameMoves, like counting hints, don't count. It is safe to call
+	// concurrently from several processes, the call made while another one is in
+	// progress marks nothing. Returns the number of domain.Game entities marked.
+	Abandon(ctx context.Context, before time.Time) (int, error)
+
+	// Moves returns domain.GameMoves of the domain.Game ordered by ply. Returns
+	// errorx.NotFound if the domain.Game couldn't be found.
+	Moves(ctx context.Context, id domain.GameId) (domain.GameMoves, error)
 
 	// Delete deletes a domain.Game by the domain.GameId. Returns errorx.NotFound if the
 	// domain.Game couldn't be found.
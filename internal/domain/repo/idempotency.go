@@ -0,0 +1,20 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
+
+// IdempotencyRepository keeps domain.IdempotencyKey entities.
+type IdempotencyRepository interface {
+	// Reserve reserves the domain.IdempotencyKey unless a not expired one having the same
+	// key exists. Returns the existing domain.IdempotencyKey or nil if reserved.
+	Reserve(ctx context.Context, key *domain.IdempotencyKey) (*domain.IdempotencyKey, error)
+
+	// Complete sets the domain.IdempotentResponse of the reserved key.
+	Complete(ctx context.Context, key string, response *domain.IdempotentResponse) error
+
+	// Release deletes the reserved key, so that the request can be repeated.
+	Release(ctx context.Context, key string) error
+}
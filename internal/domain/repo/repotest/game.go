@@ -0,0 +1,632 @@
+// Package repotest implements the conformance suites checking that the repository
+// implementations follow the contracts of the repo package interfaces.
+package repotest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo"
+)
+
+// TestGameRepository runs the conformance suite of repo.GameRepository. The newRepo
+// creates the repository to test, it may be shared by the tests, so they only check
+// the games they create. Abandon marks every idle running game though, so the
+// repository must not keep the games in use.
+func TestGameRepository(t *testing.T, newRepo func(t *testing.T) repo.GameRepository) {
+	for _, i := range []struct {
+		name string
+		test func(t *testing.T, r repo.GameRepository)
+	}{
+		{"CreateGet", testCreateGet},
+		{"CreateAll", testCreateAll},
+		{"CreateExisting", testCreateExisting},
+		{"CreateDeleted", testCreateDeleted},
+		{"GetNotFound", testGetNotFound},
+		{"AllOrder", testAllOrder},
+		{"Delete", testDelete},
+		{"DeleteTwice", testDeleteTwice},
+		{"UpdateMoves", testUpdateMoves},
+		{"UpdateNotFound", testUpdateNotFound},
+		{"UpdateConflict", testUpdateConflict},
+		{"UpdateConcurrently", testUpdateConcurrently},
+		{"Rewind", testRewind},
+		{"Abandon", testAbandon},
+		{"AbandonByMoves", testAbandonByMoves},
+	} {
+		i := i
+		t.Run(i.name, func(t *testing.T) {
+			i.test(t, newRepo(t))
+		})
+	}
+}
+
+func testCreateGet(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	p := NewGame(time.Now())
+	mustCreate(t, r, p, NewMoves(p, 3))
+	g := NewGame(time.Now())
+	w := 4
+	g.WinningMove = &w
+	g.WinningLine = &domain.GameBoardLine{Kind: domain.GameBoardLineKindRow, Cells: []int{3, 4, 5}}
+	g.Parent = &domain.GameParent{Id: p.Id, Ply: 2}
+	mustCreate(t, r, g, NewMoves(g, 1))
+	if g.Version != 1 {
+		t.Fatalf("Create() version = %d, want 1", g.Version)
+	}
+
+	v, err := r.Get(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	checkGame(t, v, g)
+}
+
+func testCreateAll(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	a, b := NewGame(time.Now()), NewGame(time.Now())
+	m := []domain.GameMoves{NewMoves(a, 1), NewMoves(b, 3)}
+	err := r.CreateAll(ctx, domain.Games{a, b}, m)
+	if err != nil {
+		t.Fatalf("CreateAll() error = %v", err)
+	}
+	for n, i := range []*domain.Game{a, b} {
+		if i.Version != 1 {
+			t.Fatalf("CreateAll() version = %d, want 1", i.Version)
+		}
+		v, err := r.Get(ctx, i.Id)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		checkGame(t, v, i)
+		s, err := r.Moves(ctx, i.Id)
+		if err != nil {
+			t.Fatalf("Moves() error = %v", err)
+		}
+		checkMoves(t, s, m[n])
+	}
+
+	// Nothing is created if a game is created already or repeated.
+	c := NewGame(time.Now())
+	d := *c
+	for _, i := range []domain.Games{{c, a}, {c, &d}} {
+		err = r.CreateAll(ctx, i, []domain.GameMoves{nil, nil})
+		if !errorx.IsConflict(err) {
+			t.Fatalf("CreateAll() error = %v, want Conflict", err)
+		}
+		_, err = r.Get(ctx, c.Id)
+		if !errorx.IsNotFound(err) {
+			t.Fatalf("Get() error = %v, want NotFound", err)
+		}
+	}
+}
+
+func testCreateExisting(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	g := NewGame(time.Now())
+	mustCreate(t, r, g, NewMoves(g, 1))
+
+	v := NewGame(time.Now())
+	v.Id = g.Id
+	v.Board = domain.MustGameBoardFromString("X--------")
+	err := r.Create(ctx, v, NewMoves(v, 3))
+	if !errorx.IsConflict(err) {
+		t.Fatalf("Create() error = %v, want Conflict", err)
+	}
+
+	// The existing game is kept.
+	v, err = r.Get(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	checkGame(t, v, g)
+	s, err := r.Moves(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Moves() error = %v", err)
+	}
+	checkMoves(t, s, NewMoves(g, 1))
+}
+
+func testCreateDeleted(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	g := NewGame(time.Now())
+	mustCreate(t, r, g, NewMoves(g, 1))
+	err := r.Delete(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	// The identifier of the deleted game can't be used again.
+	v := NewGame(time.Now())
+	v.Id = g.Id
+	err = r.Create(ctx, v, nil)
+	if !errorx.IsConflict(err) {
+		t.Fatalf("Create() error = %v, want Conflict", err)
+	}
+	err = r.CreateAll(ctx, domain.Games{v}, []domain.GameMoves{nil})
+	if !errorx.IsConflict(err) {
+		t.Fatalf("CreateAll() error = %v, want Conflict", err)
+	}
+	_, err = r.Get(ctx, g.Id)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Get() error = %v, want NotFound", err)
+	}
+}
+
+func testGetNotFound(t *testing.T, r repo.GameRepository) {
+	_, err := r.Get(context.Background(), domain.NewGameId())
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Get() error = %v, want NotFound", err)
+	}
+	_, err = r.Moves(context.Background(), domain.NewGameId())
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Moves() error = %v, want NotFound", err)
+	}
+}
+
+func testAllOrder(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	// Create games out of the creation time order.
+	c := time.Now().Add(-time.Hour)
+	s := []*domain.Game{
+		NewGame(c.Add(2 * time.Minute)),
+		NewGame(c),
+		NewGame(c.Add(time.Minute)),
+	}
+	for _, i := range s {
+		mustCreate(t, r, i, nil)
+	}
+
+	v, err := r.All(ctx)
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	var ids []domain.GameId
+	for _, i := range v {
+		for _, j := range s {
+			if i.Id == j.Id {
+				ids = append(ids, i.Id)
+			}
+		}
+	}
+	want := []domain.GameId{s[1].Id, s[2].Id, s[0].Id}
+	if len(ids) != len(want) {
+		t.Fatalf("All() returned %d of %d games", len(ids), len(want))
+	}
+	for n := range want {
+		if ids[n] != want[n] {
+			t.Fatalf("All() order = %v, want %v", ids, want)
+		}
+	}
+}
+
+func testDelete(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	g := NewGame(time.Now())
+	mustCreate(t, r, g, NewMoves(g, 1))
+
+	err := r.Delete(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	_, err = r.Get(ctx, g.Id)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Get() error = %v, want NotFound", err)
+	}
+	_, err = r.Moves(ctx, g.Id)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Moves() error = %v, want NotFound", err)
+	}
+	err = r.Update(ctx, g, nil)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Update() error = %v, want NotFound", err)
+	}
+	err = r.Rewind(ctx, g, 0)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Rewind() error = %v, want NotFound", err)
+	}
+	v, err := r.All(ctx)
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	for _, i := range v {
+		if i.Id == g.Id {
+			t.Fatalf("All() returned deleted game")
+		}
+	}
+	_, err = r.Abandon(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Abandon() error = %v", err)
+	}
+	_, err = r.Get(ctx, g.Id)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Get() after Abandon() error = %v, want NotFound", err)
+	}
+}
+
+func testDeleteTwice(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	g := NewGame(time.Now())
+	mustCreate(t, r, g, nil)
+
+	err := r.Delete(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	err = r.Delete(ctx, g.Id)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("second Delete() error = %v, want NotFound", err)
+	}
+	err = r.Delete(ctx, domain.NewGameId())
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Delete() of missing game error = %v, want NotFound", err)
+	}
+}
+
+func testUpdateMoves(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	g := NewGame(time.Now())
+	m := NewMoves(g, 3)
+	mustCreate(t, r, g, m[:1])
+
+	g.Board = domain.MustGameBoardFromString("0---XX---")
+	g.LastMover = domain.GamePlayerHuman
+	g.Hints = 2
+	g.Undos = 1
+	err := r.Update(ctx, g, m[1:])
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if g.Version != 2 {
+		t.Fatalf("Update() version = %d, want 2", g.Version)
+	}
+
+	v, err := r.Get(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	checkGame(t, v, g)
+
+	s, err := r.Moves(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Moves() error = %v", err)
+	}
+	checkMoves(t, s, m)
+
+	// Updates without moves keep the moves.
+	err = r.Update(ctx, g, nil)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	s, err = r.Moves(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Moves() error = %v", err)
+	}
+	checkMoves(t, s, m)
+}
+
+func testUpdateNotFound(t *testing.T, r repo.GameRepository) {
+	g := NewGame(time.Now())
+	g.Version = 1
+	err := r.Update(context.Background(), g, nil)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Update() error = %v, want NotFound", err)
+	}
+}
+
+func testUpdateConflict(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	g := NewGame(time.Now())
+	mustCreate(t, r, g, nil)
+
+	v, err := r.Get(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	err = r.Update(ctx, g, nil)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// The game got before the update is stale.
+	v.Hints = 5
+	err = r.Update(ctx, v, NewMoves(v, 1))
+	if !errorx.IsConflict(err) {
+		t.Fatalf("Update() error = %v, want Conflict", err)
+	}
+	err = r.Rewind(ctx, v, 0)
+	if !errorx.IsConflict(err) {
+		t.Fatalf("Rewind() error = %v, want Conflict", err)
+	}
+
+	// Nothing is changed by the failed updates.
+	v, err = r.Get(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	checkGame(t, v, g)
+	s, err := r.Moves(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Moves() error = %v", err)
+	}
+	if len(s) != 0 {
+		t.Fatalf("Moves() returned %d moves, want 0", len(s))
+	}
+}
+
+func testUpdateConcurrently(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	g := NewGame(time.Now())
+	mustCreate(t, r, g, nil)
+
+	// Every update is based on the same version, so only one of them succeeds.
+	const n = 10
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ok  int
+		bad []error
+	)
+	for i := 0; i < n; i++ {
+		v := *g
+		v.Hints = i + 1
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := r.Update(ctx, &v, NewMoves(&v, 1))
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				ok++
+			case !errorx.IsConflict(err):
+				bad = append(bad, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(bad) > 0 {
+		t.Fatalf("Update() errors = %v, want Conflict", bad)
+	}
+	if ok != 1 {
+		t.Fatalf("%d of %d concurrent updates succeeded, want 1", ok, n)
+	}
+	v, err := r.Get(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if v.Version != 2 {
+		t.Fatalf("Get() version = %d, want 2", v.Version)
+	}
+	s, err := r.Moves(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Moves() error = %v", err)
+	}
+	if len(s) != 1 {
+		t.Fatalf("Moves() returned %d moves, want 1", len(s))
+	}
+}
+
+func testRewind(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	g := NewGame(time.Now())
+	m := NewMoves(g, 3)
+	mustCreate(t, r, g, m)
+
+	g.Board = domain.MustGameBoardFromString("----X----")
+	g.Undos = 1
+	err := r.Rewind(ctx, g, 1)
+	if err != nil {
+		t.Fatalf("Rewind() error = %v", err)
+	}
+	if g.Version != 2 {
+		t.Fatalf("Rewind() version = %d, want 2", g.Version)
+	}
+
+	v, err := r.Get(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	checkGame(t, v, g)
+	s, err := r.Moves(ctx, g.Id)
+	if err != nil {
+		t.Fatalf("Moves() error = %v", err)
+	}
+	checkMoves(t, s, m[:1])
+}
+
+func testAbandon(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	a := NewGame(time.Now())
+	mustCreate(t, r, a, nil)
+	b := NewGame(time.Now())
+	b.Status = domain.GameStatusDraw
+	mustCreate(t, r, b, nil)
+
+	// Nothing is idle for long.
+	_, err := r.Abandon(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Abandon() error = %v", err)
+	}
+	v, err := r.Get(ctx, a.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if v.Status != domain.GameStatusRunning {
+		t.Fatalf("Get() status = %s, want %s", v.Status, domain.GameStatusRunning)
+	}
+
+	n, err := r.Abandon(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Abandon() error = %v", err)
+	}
+	if n < 1 {
+		t.Fatalf("Abandon() = %d, want at least 1", n)
+	}
+	v, err = r.Get(ctx, a.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if v.Status != domain.GameStatusAbandoned || v.Version != a.Version+1 {
+		t.Fatalf("Get() status = %s, version = %d, want %s, %d", v.Status, v.Version, domain.GameStatusAbandoned, a.Version+1)
+	}
+	// The abandoned game can't be updated with the stale version.
+	err = r.Update(ctx, a, nil)
+	if !errorx.IsConflict(err) {
+		t.Fatalf("Update() error = %v, want Conflict", err)
+	}
+	// Finished games are not abandoned.
+	v, err = r.Get(ctx, b.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if v.Status != domain.GameStatusDraw {
+		t.Fatalf("Get() status = %s, want %s", v.Status, domain.GameStatusDraw)
+	}
+}
+
+func testAbandonByMoves(t *testing.T, r repo.GameRepository) {
+	ctx := context.Background()
+	a, b := NewGame(time.Now()), NewGame(time.Now())
+	mustCreate(t, r, a, NewMoves(a, 1))
+	mustCreate(t, r, b, NewMoves(b, 1))
+	time.Sleep(10 * time.Millisecond)
+	before := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	// The hint taken doesn't keep the game from being abandoned, the move made does.
+	a.Hints++
+	err := r.Update(ctx, a, nil)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	b.Board = domain.MustGameBoardFromString("0---XX---")
+	err = r.Update(ctx, b, NewMoves(b, 3)[1:])
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	_, err = r.Abandon(ctx, before)
+	if err != nil {
+		t.Fatalf("Abandon() error = %v", err)
+	}
+	for _, i := range []struct {
+		game   *domain.Game
+		status domain.GameStatus
+	}{
+		{a, domain.GameStatusAbandoned},
+		{b, domain.GameStatusRunning},
+	} {
+		v, err := r.Get(ctx, i.game.Id)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if v.Status != i.status {
+			t.Fatalf("Get() status = %s, want %s", v.Status, i.status)
+		}
+	}
+}
+
+// NewGame creates a running game having the user's first move made. The creation time
+// is rounded to the precision every storage keeps.
+func NewGame(created time.Time) *domain.Game {
+	return &domain.Game{
+		Id:         domain.NewGameId(),
+		Board:      domain.MustGameBoardFromString("----X----"),
+		Start:      domain.MustGameBoardFromString("---------"),
+		Status:     domain.GameStatusRunning,
+		Char:       domain.GameBoardCharNought,
+		FirstMover: domain.GamePlayerHuman,
+		LastMover:  domain.GamePlayerHuman,
+		Strategy:   "perfect",
+		UndoLimit:  3,
+		Created:    created.UTC().Truncate(time.Millisecond),
+	}
+}
+
+// NewMoves creates n moves of the game alternating the sides starting with the user.
+func NewMoves(game *domain.Game, n int) domain.GameMoves {
+	s := make(domain.GameMoves, n)
+	for i := range s {
+		s[i] = &domain.GameMove{
+			Ply:    i + 1,
+			Player: domain.GamePlayerHuman,
+			Cell:   []int{4, 0, 5, 3, 8, 1, 2, 6, 7}[i],
+			Char:   game.PlayerChar(),
+			Time:   game.Created.Add(time.Duration(i) * time.Second),
+		}
+		if i%2 == 1 {
+			s[i].Player = domain.GamePlayerComputer
+			s[i].Char = game.Char
+		}
+	}
+	return s
+}
+
+func mustCreate(t *testing.T, r repo.GameRepository, game *domain.Game, moves domain.GameMoves) {
+	t.Helper()
+	err := r.Create(context.Background(), game, moves)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+}
+
+func checkGame(t *testing.T, got, want *domain.Game) {
+	t.Helper()
+	switch {
+	case got.Id != want.Id:
+		t.Fatalf("Id = %s, want %s", got.Id, want.Id)
+	case got.Board.String() != want.Board.String() || got.Board.Win() != want.Board.Win():
+		t.Fatalf("Board = %s, want %s", got.Board.String(), want.Board.String())
+	case got.Start.String() != want.Start.String():
+		t.Fatalf("Start = %s, want %s", got.Start.String(), want.Start.String())
+	case got.Status != want.Status:
+		t.Fatalf("Status = %s, want %s", got.Status, want.Status)
+	case got.Char != want.Char:
+		t.Fatalf("Char = %s, want %s", got.Char, want.Char)
+	case got.FirstMover != want.FirstMover || got.LastMover != want.LastMover:
+		t.Fatalf("movers = %s, %s, want %s, %s", got.FirstMover, got.LastMover, want.FirstMover, want.LastMover)
+	case (got.WinningMove == nil) != (want.WinningMove == nil) || got.WinningMove != nil && *got.WinningMove != *want.WinningMove:
+		t.Fatalf("WinningMove = %v, want %v", got.WinningMove, want.WinningMove)
+	case (got.WinningLine == nil) != (want.WinningLine == nil) || got.WinningLine != nil && got.WinningLine.Kind != want.WinningLine.Kind:
+		t.Fatalf("WinningLine = %v, want %v", got.WinningLine, want.WinningLine)
+	case got.Winner != want.Winner:
+		t.Fatalf("Winner = %s, want %s", got.Winner, want.Winner)
+	case got.Strategy != want.Strategy:
+		t.Fatalf("Strategy = %s, want %s", got.Strategy, want.Strategy)
+	case got.Hints != want.Hints || got.UndoLimit != want.UndoLimit || got.Undos != want.Undos:
+		t.Fatalf("counters = %d, %d, %d, want %d, %d, %d", got.Hints, got.UndoLimit, got.Undos, want.Hints, want.UndoLimit, want.Undos)
+	case !got.Created.Equal(want.Created):
+		t.Fatalf("Created = %s, want %s", got.Created, want.Created)
+	case (got.Parent == nil) != (want.Parent == nil) || got.Parent != nil && *got.Parent != *want.Parent:
+		t.Fatalf("Parent = %v, want %v", got.Parent, want.Parent)
+	case got.Version != want.Version:
+		t.Fatalf("Version = %d, want %d", got.Version, want.Version)
+	}
+	if got.WinningLine != nil {
+		for n, i := range want.WinningLine.Cells {
+			if n >= len(got.WinningLine.Cells) || got.WinningLine.Cells[n] != i {
+				t.Fatalf("WinningLine = %v, want %v", got.WinningLine.Cells, want.WinningLine.Cells)
+			}
+		}
+	}
+}
+
+func checkMoves(t *testing.T, got, want domain.GameMoves) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Moves() returned %d moves, want %d", len(got), len(want))
+	}
+	for n, i := range want {
+		g := got[n]
+		if g.Ply != i.Ply || g.Player != i.Player || g.Cell != i.Cell || g.Char != i.Char || !g.Time.Equal(i.Time) {
+			t.Fatalf("move %d = %+v, want %+v", n, g, i)
+		}
+	}
+}
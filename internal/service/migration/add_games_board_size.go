@@ -0,0 +1,20 @@
+package migration
+
+import "database/sql"
+
+type addGamesBoardSize struct{}
+
+func (m *addGamesBoardSize) name() string {
+	return "20261018_100000_add_games_board_size"
+}
+
+func (m *addGamesBoardSize) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games"
+    ALTER COLUMN "board" TYPE VARCHAR(100),
+    ADD COLUMN "size" SMALLINT NOT NULL DEFAULT 3,
+    ADD COLUMN "win" SMALLINT NOT NULL DEFAULT 3`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
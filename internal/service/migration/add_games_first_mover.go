@@ -0,0 +1,36 @@
+package migration
+
+import "database/sql"
+
+type addGamesFirstMover struct{}
+
+func (m *addGamesFirstMover) name() string {
+	return "20261018_120000_add_games_first_mover"
+}
+
+func (m *addGamesFirstMover) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games" ADD COLUMN "first_mover" VARCHAR(16) NOT NULL DEFAULT 'COMPUTER'`)
+	if err != nil {
+		return err
+	}
+	// The first stored move is made by the first mover.
+	_, err = tx.Exec(`UPDATE "games" SET "first_mover" = "game_moves"."player"
+FROM "game_moves"
+WHERE "game_moves"."game_id" = "games"."id"
+  AND "game_moves"."ply" = (SELECT MIN("ply") FROM "game_moves" WHERE "game_id" = "games"."id")`)
+	if err != nil {
+		return err
+	}
+	// The games without stored moves are decided by the pieces. The computer has replied
+	// to every user's move, so it has started the game if it has made more moves, or the
+	// same number of moves if the user has won with the last one.
+	_, err = tx.Exec(`UPDATE "games" SET "first_mover" = 'HUMAN'
+WHERE NOT EXISTS (SELECT 1 FROM "game_moves" WHERE "game_id" = "games"."id")
+  AND (2 * (LENGTH("board") - LENGTH(REPLACE("board", "char", ''))) < LENGTH(REPLACE("board", '-', ''))
+    OR 2 * (LENGTH("board") - LENGTH(REPLACE("board", "char", ''))) = LENGTH(REPLACE("board", '-', ''))
+      AND NOT ("status" = 'X_WON' AND "char" = '0' OR "status" = 'O_WON' AND "char" = 'X'))`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package migration
+
+import "database/sql"
+
+type addGamesHints struct{}
+
+func (m *addGamesHints) name() string {
+	return "20261018_160000_add_games_hints"
+}
+
+func (m *addGamesHints) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games" ADD COLUMN "hints" INTEGER NOT NULL DEFAULT 0`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
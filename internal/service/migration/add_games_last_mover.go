@@ -0,0 +1,41 @@
+package migration
+
+import "database/sql"
+
+type addGamesLastMover struct{}
+
+func (m *addGamesLastMover) name() string {
+	return "20261018_130000_add_games_last_mover"
+}
+
+func (m *addGamesLastMover) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games"
+    ADD COLUMN "last_mover" VARCHAR(16) NOT NULL DEFAULT '',
+    ADD COLUMN "winning_move" SMALLINT`)
+	if err != nil {
+		return err
+	}
+	// The last stored move is made by the last mover.
+	_, err = tx.Exec(`UPDATE "games" SET "last_mover" = "game_moves"."player"
+FROM "game_moves"
+WHERE "game_moves"."game_id" = "games"."id"
+  AND "game_moves"."ply" = (SELECT MAX("ply") FROM "game_moves" WHERE "game_id" = "games"."id")`)
+	if err != nil {
+		return err
+	}
+	// The games without stored moves are decided by the pieces. The side having made
+	// more moves has moved last, the side other than the first mover if both have made
+	// the same number of moves.
+	_, err = tx.Exec(`UPDATE "games" SET "last_mover" = CASE
+    WHEN LENGTH(REPLACE("board", '-', '')) = 0 THEN ''
+    WHEN 2 * (LENGTH("board") - LENGTH(REPLACE("board", "char", ''))) > LENGTH(REPLACE("board", '-', '')) THEN 'COMPUTER'
+    WHEN 2 * (LENGTH("board") - LENGTH(REPLACE("board", "char", ''))) < LENGTH(REPLACE("board", '-', '')) THEN 'HUMAN'
+    WHEN "first_mover" = 'COMPUTER' THEN 'HUMAN'
+    ELSE 'COMPUTER'
+END
+WHERE NOT EXISTS (SELECT 1 FROM "game_moves" WHERE "game_id" = "games"."id")`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
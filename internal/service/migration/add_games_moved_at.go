@@ -0,0 +1,26 @@
+package migration
+
+import "database/sql"
+
+type addGamesMovedAt struct{}
+
+func (m *addGamesMovedAt) name() string {
+	return "20261018_230000_add_games_moved_at"
+}
+
+func (m *addGamesMovedAt) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games" ADD COLUMN "moved_at" TIMESTAMP NULL`)
+	if err != nil {
+		return err
+	}
+	// The last update is the closest known time of the last move.
+	_, err = tx.Exec(`UPDATE "games" SET "moved_at" = "updated_at"`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`ALTER TABLE "games" ALTER COLUMN "moved_at" SET NOT NULL`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
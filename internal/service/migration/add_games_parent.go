@@ -0,0 +1,17 @@
+package migration
+
+import "database/sql"
+
+type addGamesParent struct{}
+
+func (m *addGamesParent) name() string {
+	return "20261018_190000_add_games_parent"
+}
+
+func (m *addGamesParent) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games" ADD COLUMN "parent_id" UUID NULL REFERENCES "games" ("id"), ADD COLUMN "parent_ply" INTEGER NULL`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package migration
+
+import "database/sql"
+
+type addGamesStartBoard struct{}
+
+func (m *addGamesStartBoard) name() string {
+	return "20261018_170000_add_games_start_board"
+}
+
+func (m *addGamesStartBoard) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games" ADD COLUMN "start_board" VARCHAR(100)`)
+	if err != nil {
+		return err
+	}
+	// All the games so far have started from the blank board.
+	_, err = tx.Exec(`UPDATE "games" SET "start_board" = REPEAT('-', LENGTH("board"))`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`ALTER TABLE "games" ALTER COLUMN "start_board" SET NOT NULL`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package migration
+
+import "database/sql"
+
+type addGamesStrategy struct{}
+
+func (m *addGamesStrategy) name() string {
+	return "20261018_150000_add_games_strategy"
+}
+
+func (m *addGamesStrategy) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games" ADD COLUMN "strategy" VARCHAR(32) NOT NULL DEFAULT 'perfect'`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
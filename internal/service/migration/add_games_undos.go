@@ -0,0 +1,17 @@
+package migration
+
+import "database/sql"
+
+type addGamesUndos struct{}
+
+func (m *addGamesUndos) name() string {
+	return "20261018_180000_add_games_undos"
+}
+
+func (m *addGamesUndos) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games" ADD COLUMN "undo_limit" INTEGER NOT NULL DEFAULT 3, ADD COLUMN "undos" INTEGER NOT NULL DEFAULT 0`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
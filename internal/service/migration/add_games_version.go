@@ -0,0 +1,17 @@
+package migration
+
+import "database/sql"
+
+type addGamesVersion struct{}
+
+func (m *addGamesVersion) name() string {
+	return "20261018_210000_add_games_version"
+}
+
+func (m *addGamesVersion) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games" ADD COLUMN "version" INTEGER NOT NULL DEFAULT 1`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
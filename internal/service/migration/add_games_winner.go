@@ -0,0 +1,21 @@
+package migration
+
+import "database/sql"
+
+type addGamesWinner struct{}
+
+func (m *addGamesWinner) name() string {
+	return "20261018_200000_add_games_winner"
+}
+
+func (m *addGamesWinner) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games" ADD COLUMN "winner" VARCHAR(1) NOT NULL DEFAULT ''`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE "games" SET "winner" = CASE "status" WHEN 'X_WON' THEN 'X' ELSE '0' END WHERE "status" IN ('X_WON', 'O_WON')`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package migration
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
+
+type addGamesWinningLine struct{}
+
+func (m *addGamesWinningLine) name() string {
+	return "20261018_140000_add_games_winning_line"
+}
+
+func (m *addGamesWinningLine) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE "games"
+    ADD COLUMN "winning_line_kind" VARCHAR(16) NOT NULL DEFAULT '',
+    ADD COLUMN "winning_line" VARCHAR(64) NOT NULL DEFAULT ''`)
+	if err != nil {
+		return err
+	}
+
+	// Detect winning lines of the games already won.
+	v, err := tx.Query(`SELECT "id", "board", "win" FROM "games" WHERE "status" IN ('X_WON', 'O_WON')`)
+	if err != nil {
+		return err
+	}
+	l := make(map[string]*domain.GameBoardLine)
+	for v.Next() {
+		var (
+			id    string
+			board string
+			win   int
+		)
+		err = v.Scan(&id, &board, &win)
+		if err != nil {
+			v.Close()
+			return err
+		}
+		b, err := domain.GameBoardFromStringWithWin(board, win)
+		if err != nil {
+			v.Close()
+			return err
+		}
+		if w := b.WinningLine(); w != nil {
+			l[id] = w
+		}
+	}
+	v.Close()
+	if err = v.Err(); err != nil {
+		return err
+	}
+
+	for id, w := range l {
+		s := make([]string, len(w.Cells))
+		for n, i := range w.Cells {
+			s[n] = strconv.Itoa(i)
+		}
+		_, err = tx.Exec(`UPDATE "games" SET "winning_line_kind" = $1, "winning_line" = $2 WHERE "id" = $3`, w.Kind, strings.Join(s, ","), id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package migration
+
+import "database/sql"
+
+type createGameMovesTable struct{}
+
+func (m *createGameMovesTable) name() string {
+	return "20261018_110000_create_game_moves_table"
+}
+
+func (m *createGameMovesTable) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE "game_moves"
+(
+    "game_id" UUID NOT NULL REFERENCES "games" ("id"),
+    "ply" SMALLINT NOT NULL,
+    "player" VARCHAR(16) NOT NULL,
+    "cell" SMALLINT NOT NULL,
+    "char" CHAR(1) NOT NULL,
+    "created_at" TIMESTAMP NOT NULL,
+    PRIMARY KEY ("game_id", "ply")
+)`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package migration
+
+import "database/sql"
+
+type createIdempotencyKeysTable struct{}
+
+func (m *createIdempotencyKeysTable) name() string {
+	return "20261018_220000_create_idempotency_keys_table"
+}
+
+func (m *createIdempotencyKeysTable) up(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE "idempotency_keys"
+(
+    "key" VARCHAR(255) PRIMARY KEY,
+    "fingerprint" VARCHAR(64) NOT NULL,
+    "status" SMALLINT NULL,
+    "header" TEXT NOT NULL DEFAULT '',
+    "body" BYTEA NULL,
+    "created_at" TIMESTAMP NOT NULL,
+    "expires_at" TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX "idempotency_keys_expires_at_idx" ON "idempotency_keys" ("expires_at")`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
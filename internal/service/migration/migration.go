@@ -25,6 +25,20 @@ func Run(db *sql.DB) error {
 
 	for _, i := range []migration{
 		&createGamesTable{},
+		&addGamesBoardSize{},
+		&createGameMovesTable{},
+		&addGamesFirstMover{},
+		&addGamesLastMover{},
+		&addGamesWinningLine{},
+		&addGamesStrategy{},
+		&addGamesHints{},
+		&addGamesStartBoard{},
+		&addGamesUndos{},
+		&addGamesParent{},
+		&addGamesWinner{},
+		&addGamesVersion{},
+		&createIdempotencyKeysTable{},
+		&addGamesMovedAt{},
 	} {
 		if m[i.name()] {
 			continue
@@ -0,0 +1,608 @@
+// Package file implements the repositories keeping entities in files of a local
+// directory. Games are kept in an append-only log of their mutations, which is
+// compacted from time to time. The log is synced after every write and its torn tail
+// left by a crash is dropped on opening, so that no acknowledged write is lost.
+package file
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+)
+
+const (
+	gamesFile = "games.log"
+	lockFile  = "lock"
+	// compactAfter is the number of log entries superseded by later ones the log is
+	// compacted after.
+	compactAfter = 1000
+)
+
+var errClosed = errors.New("repository is closed")
+
+type gameEntry struct {
+	game  *domain.Game
+	moves domain.GameMoves
+	// movedAt is the time of the last move made or taken back, games are abandoned
+	// by it.
+	movedAt time.Time
+	// deleted marks the tombstone of a deleted game, it keeps the identifier from
+	// being used again.
+	deleted bool
+}
+
+// GameRepository is a repo.GameRepository keeping games in a directory. It is safe
+// for concurrent use, but the directory can't be shared by several processes.
+type GameRepository struct {
+	dir  string
+	lock *os.File
+
+	mu    sync.RWMutex
+	games map[domain.GameId]*gameEntry
+	// order is the order the games have been created in.
+	order []domain.GameId
+	f     *os.File
+	// size is the length of the log written successfully.
+	size int64
+	// entries is the number of entries in the log.
+	entries int
+	// compactAfter is the number of superseded entries the log is compacted after.
+	compactAfter int
+	// err fails every write once the log can't be written consistently.
+	err error
+}
+
+// NewGameRepository opens the repository kept in the directory, creating the
+// directory if it doesn't exist. The repository must be closed once it is not needed.
+func NewGameRepository(dir string) (*GameRepository, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	l, err := os.OpenFile(filepath.Join(dir, lockFile), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	err = lock(l)
+	if err != nil {
+		_ = l.Close()
+		return nil, fmt.Errorf("data directory is used by another process: %w", err)
+	}
+
+	r := &GameRepository{
+		dir:          dir,
+		lock:         l,
+		games:        make(map[domain.GameId]*gameEntry),
+		compactAfter: compactAfter,
+	}
+	err = r.open()
+	if err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Close closes the log. The repository can't be used after closing.
+func (r *GameRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == errClosed {
+		return nil
+	}
+	r.err = errClosed
+	err := r.f.Close()
+	if e := r.lock.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (r *GameRepository) All(ctx context.Context) (domain.Games, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var s domain.Games
+	for _, i := range r.order {
+		s = append(s, r.games[i].game.Clone())
+	}
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Created.Before(s[j].Created)
+	})
+	return s, nil
+}
+
+func (r *GameRepository) Get(ctx context.Context, id domain.GameId) (*domain.Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	g, err := r.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return g.game.Clone(), nil
+}
+
+func (r *GameRepository) Create(ctx context.Context, game *domain.Game, moves domain.GameMoves) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.games[game.Id]; ok {
+		return errorx.WrapInConflict(fmt.Errorf("game already exists: %s", game.Id))
+	}
+	err := r.write(newCreateEntry(game, moves))
+	if err != nil {
+		return err
+	}
+	game.Version = 1
+	return nil
+}
+
+func (r *GameRepository) CreateAll(ctx context.Context, games domain.Games, moves []domain.GameMoves) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := make(map[domain.GameId]bool, len(games))
+	e := &entry{Op: opBatch, Time: time.Now()}
+	for n, i := range games {
+		if _, ok := r.games[i.Id]; ok || m[i.Id] {
+			return errorx.WrapInConflict(fmt.Errorf("game already exists: %s", i.Id))
+		}
+		m[i.Id] = true
+		e.Entries = append(e.Entries, newCreateEntry(i, moves[n]))
+	}
+	err := r.write(e)
+	if err != nil {
+		return err
+	}
+	for _, i := range games {
+		i.Version = 1
+	}
+	return nil
+}
+
+func (r *GameRepository) Update(ctx context.Context, game *domain.Game, moves domain.GameMoves) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, err := r.update(game)
+	if err != nil {
+		return err
+	}
+	err = r.write(&entry{
+		Op:    opUpdate,
+		Id:    string(v.Id),
+		Game:  newGameRecord(v),
+		Moves: newMoveRecords(moves),
+		Time:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	game.Version = v.Version
+	return nil
+}
+
+func (r *GameRepository) Rewind(ctx context.Context, game *domain.Game, ply int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, err := r.update(game)
+	if err != nil {
+		return err
+	}
+	err = r.write(&entry{
+		Op:   opRewind,
+		Id:   string(v.Id),
+		Game: newGameRecord(v),
+		Ply:  ply,
+		Time: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	game.Version = v.Version
+	return nil
+}
+
+func (r *GameRepository) Abandon(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := time.Now()
+	var s []*entry
+	for _, i := range r.order {
+		g := r.games[i]
+		if g.game.Status != domain.GameStatusRunning || !g.movedAt.Before(before) {
+			continue
+		}
+		v := g.game.Clone()
+		v.Status = domain.GameStatusAbandoned
+		v.Version++
+		s = append(s, &entry{
+			Op:   opUpdate,
+			Id:   string(v.Id),
+			Game: newGameRecord(v),
+			Time: t,
+		})
+	}
+	if len(s) == 0 {
+		return 0, nil
+	}
+	err := r.write(s...)
+	if err != nil {
+		return 0, err
+	}
+	return len(s), nil
+}
+
+func (r *GameRepository) Moves(ctx context.Context, id domain.GameId) (domain.GameMoves, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	g, err := r.get(id)
+	if err != nil {
+		return nil, err
+	}
+	s := g.moves.Clone()
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Ply < s[j].Ply
+	})
+	return s, nil
+}
+
+func (r *GameRepository) Delete(ctx context.Context, id domain.GameId) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err := r.get(id)
+	if err != nil {
+		return err
+	}
+	return r.write(&entry{
+		Op:   opDelete,
+		Id:   string(id),
+		Time: time.Now(),
+	})
+}
+
+// get returns the game not deleted. The lock must be held.
+func (r *GameRepository) get(id domain.GameId) (*gameEntry, error) {
+	g, ok := r.games[id]
+	if !ok || g.deleted {
+		return nil, errorx.NewNotFound()
+	}
+	return g, nil
+}
+
+// update returns the copy of the game to keep if its version matches. The version of
+// the copy is incremented. The lock must be held.
+func (r *GameRepository) update(game *domain.Game) (*domain.Game, error) {
+	g, err := r.get(game.Id)
+	if err != nil {
+		return nil, err
+	}
+	if g.game.Version != game.Version {
+		return nil, errorx.WrapInConflict(fmt.Errorf("game has been updated concurrently"))
+	}
+	v := game.Clone()
+	v.Version++
+	// Only the game state can be updated.
+	v.Start = g.game.Start
+	v.FirstMover = g.game.FirstMover
+	v.Strategy = g.game.Strategy
+	v.UndoLimit = g.game.UndoLimit
+	v.Created = g.game.Created
+	v.Parent = g.game.Parent
+	return v, nil
+}
+
+// write appends the entries to the log, syncs it and applies the entries. Nothing is
+// applied if the entries couldn't be written. The write lock must be held.
+func (r *GameRepository) write(entries ...*entry) error {
+	if r.err != nil {
+		return r.err
+	}
+	var (
+		buf []byte
+		err error
+	)
+	for _, i := range entries {
+		buf, err = appendEntry(buf, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = r.f.Write(buf)
+	if err != nil {
+		// Drop the entries written partially, so that the next ones follow the last
+		// complete entry.
+		if e := r.f.Truncate(r.size); e != nil {
+			r.err = fmt.Errorf("unable to write games log: %v", e)
+		}
+		return err
+	}
+	err = r.f.Sync()
+	if err != nil {
+		// What is on disk is unknown after a failed sync, nothing can be written
+		// until the log is loaded again.
+		r.err = fmt.Errorf("unable to sync games log: %v", err)
+		return err
+	}
+	r.size += int64(len(buf))
+	r.entries += len(entries)
+
+	for _, i := range entries {
+		err = r.apply(i)
+		if err != nil {
+			// The games don't match the log anymore.
+			r.err = fmt.Errorf("unable to apply games log entry: %v", err)
+			return err
+		}
+	}
+
+	if r.entries-len(r.games) >= r.compactAfter {
+		err = r.compact()
+		if err != nil {
+			log.Printf("Unable to compact games log: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// apply applies the entry to the games. The write lock must be held.
+func (r *GameRepository) apply(e *entry) error {
+	if e.Op == opBatch {
+		for _, i := range e.Entries {
+			err := r.apply(i)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	id := domain.GameId(e.Id)
+	if e.Op == opCreate {
+		if _, ok := r.games[id]; ok {
+			return errorx.WrapInConflict(fmt.Errorf("game already exists: %s", id))
+		}
+		g, err := e.Game.to(e.Id)
+		if err != nil {
+			return err
+		}
+		r.games[id] = &gameEntry{
+			game:    g,
+			moves:   movesFromRecords(e.Moves),
+			movedAt: e.Time,
+		}
+		r.order = append(r.order, id)
+		return nil
+	}
+
+	g, ok := r.games[id]
+	if !ok && e.Op == opDelete {
+		// The tombstone written by compaction.
+		r.games[id] = &gameEntry{deleted: true}
+		return nil
+	}
+	if !ok || g.deleted {
+		return fmt.Errorf("game not found: %s", id)
+	}
+	switch e.Op {
+	case opUpdate:
+		v, err := e.Game.to(e.Id)
+		if err != nil {
+			return err
+		}
+		g.game = v
+		g.moves = append(g.moves, movesFromRecords(e.Moves)...)
+		if len(e.Moves) > 0 {
+			g.movedAt = e.Time
+		}
+	case opRewind:
+		v, err := e.Game.to(e.Id)
+		if err != nil {
+			return err
+		}
+		g.game = v
+		n := 0
+		for _, i := range g.moves {
+			if i.Ply <= e.Ply {
+				g.moves[n] = i
+				n++
+			}
+		}
+		g.moves = g.moves[:n]
+		g.movedAt = e.Time
+	case opDelete:
+		r.games[id] = &gameEntry{deleted: true}
+		for n, i := range r.order {
+			if i == id {
+				r.order = append(r.order[:n], r.order[n+1:]...)
+				break
+			}
+		}
+	default:
+		return fmt.Errorf("unknown operation: %q", e.Op)
+	}
+	return nil
+}
+
+// open loads the games from the log, dropping the incomplete entries at its end, and
+// opens the log for writing.
+func (r *GameRepository) open() error {
+	p := filepath.Join(r.dir, gamesFile)
+	// The compacted log might be left unfinished.
+	err := os.Remove(p + ".tmp")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	err = syncDir(r.dir)
+	if err == nil {
+		err = r.load(f)
+	}
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.f = f
+	return nil
+}
+
+// load applies the entries of the log and truncates the log after the last complete
+// entry if the last write is torn. Returns error leaving the log untouched if an
+// entry followed by other writes is damaged, no synced entry is dropped.
+func (r *GameRepository) load(f *os.File) error {
+	rd := bufio.NewReader(f)
+	for {
+		e, n, err := readEntry(rd)
+		if err == io.EOF {
+			return nil
+		}
+		if errors.Is(err, errCorrupted) {
+			ok, e := isTail(f, r.size)
+			if e != nil {
+				return e
+			}
+			if !ok {
+				return fmt.Errorf("games log is corrupted at offset %d: %v", r.size, err)
+			}
+			st, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			log.Printf("Drop %d bytes of games log after offset %d, the last entry is incomplete.\n", st.Size()-r.size, r.size)
+			err = f.Truncate(r.size)
+			if err != nil {
+				return err
+			}
+			return f.Sync()
+		}
+		if err != nil {
+			return err
+		}
+
+		err = r.apply(e)
+		if err != nil {
+			return fmt.Errorf("unable to load games log at offset %d: %v", r.size, err)
+		}
+		r.size += n
+		r.entries++
+	}
+}
+
+// compact replaces the log with the one having an entry per game. The log is replaced
+// atomically, so that either log is found after a crash. The write lock must be held.
+func (r *GameRepository) compact() error {
+	p := filepath.Join(r.dir, gamesFile)
+	f, err := os.OpenFile(p+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	size, err := r.snapshot(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(p+".tmp", p)
+	}
+	if err != nil {
+		_ = os.Remove(p + ".tmp")
+		return err
+	}
+
+	// The old log is unlinked, the writes must go to the new one from now on.
+	err = syncDir(r.dir)
+	if err == nil {
+		f, err = os.OpenFile(p, os.O_RDWR|os.O_APPEND, 0o644)
+	}
+	if err != nil {
+		r.err = fmt.Errorf("unable to reopen games log: %v", err)
+		return err
+	}
+	_ = r.f.Close()
+	r.f = f
+	r.size = size
+	r.entries = len(r.games)
+	return nil
+}
+
+// snapshot writes an entry creating every game and a tombstone of every deleted game,
+// and returns the number of bytes written. The entries have the time of the last move,
+// so that it is kept.
+func (r *GameRepository) snapshot(w io.Writer) (int64, error) {
+	s := make([]*entry, 0, len(r.games))
+	for _, i := range r.order {
+		g := r.games[i]
+		s = append(s, &entry{
+			Op:    opCreate,
+			Id:    string(i),
+			Game:  newGameRecord(g.game),
+			Moves: newMoveRecords(g.moves),
+			Time:  g.movedAt,
+		})
+	}
+	t := time.Now()
+	for i, g := range r.games {
+		if g.deleted {
+			s = append(s, &entry{Op: opDelete, Id: string(i), Time: t})
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	var size int64
+	for _, i := range s {
+		buf, err := appendEntry(nil, i)
+		if err != nil {
+			return 0, err
+		}
+		_, err = bw.Write(buf)
+		if err != nil {
+			return 0, err
+		}
+		size += int64(len(buf))
+	}
+	return size, bw.Flush()
+}
+
+// newCreateEntry creates the entry creating the game of the first version.
+func newCreateEntry(game *domain.Game, moves domain.GameMoves) *entry {
+	v := game.Clone()
+	v.Version = 1
+	return &entry{
+		Op:    opCreate,
+		Id:    string(v.Id),
+		Game:  newGameRecord(v),
+		Moves: newMoveRecords(moves),
+		Time:  time.Now(),
+	}
+}
+
+func movesFromRecords(s []moveRecord) domain.GameMoves {
+	v := make(domain.GameMoves, len(s))
+	for n := range s {
+		v[n] = s[n].to()
+	}
+	return v
+}
@@ -0,0 +1,224 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo/repotest"
+)
+
+func TestGameRepository(t *testing.T) {
+	repotest.TestGameRepository(t, func(t *testing.T) repo.GameRepository {
+		return open(t, t.TempDir())
+	})
+}
+
+func TestGameRepositoryReopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	r := open(t, dir)
+	a, b := repotest.NewGame(time.Now()), repotest.NewGame(time.Now())
+	for _, i := range []*domain.Game{a, b} {
+		err := r.Create(ctx, i, repotest.NewMoves(i, 1))
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	a.Board = domain.MustGameBoardFromString("0---XX---")
+	err := r.Update(ctx, a, repotest.NewMoves(a, 3)[1:])
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	a.Board = domain.MustGameBoardFromString("----X----")
+	err = r.Rewind(ctx, a, 1)
+	if err != nil {
+		t.Fatalf("Rewind() error = %v", err)
+	}
+	err = r.Delete(ctx, b.Id)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	r.Close()
+
+	r = open(t, dir)
+	check(t, r, a, 1)
+	_, err = r.Get(ctx, b.Id)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Get() error = %v, want NotFound", err)
+	}
+}
+
+func TestGameRepositoryTornTail(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	r := open(t, dir)
+	a, b := repotest.NewGame(time.Now()), repotest.NewGame(time.Now())
+	for _, i := range []*domain.Game{a, b} {
+		err := r.Create(ctx, i, repotest.NewMoves(i, 1))
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	r.Close()
+
+	// Cut the last entry as if the process has crashed while writing it.
+	p := filepath.Join(dir, gamesFile)
+	st, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Truncate(p, st.Size()-5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r = open(t, dir)
+	check(t, r, a, 1)
+	_, err = r.Get(ctx, b.Id)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Get() error = %v, want NotFound", err)
+	}
+
+	// The entries written after recovery follow the last complete one.
+	c := repotest.NewGame(time.Now())
+	err = r.Create(ctx, c, repotest.NewMoves(c, 1))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	r.Close()
+
+	r = open(t, dir)
+	check(t, r, a, 1)
+	check(t, r, c, 1)
+}
+
+func TestGameRepositoryCorrupted(t *testing.T) {
+	for _, i := range []struct {
+		name   string
+		offset int
+	}{
+		{"Length", 0},
+		{"Checksum", 5},
+		{"Payload", 20},
+	} {
+		t.Run(i.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			r := open(t, dir)
+			for n := 0; n < 3; n++ {
+				g := repotest.NewGame(time.Now())
+				err := r.Create(ctx, g, repotest.NewMoves(g, 1))
+				if err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+			}
+			r.Close()
+
+			// Damage the first entry, the synced entries after it must not be dropped.
+			p := filepath.Join(dir, gamesFile)
+			b, err := os.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b[i.offset] ^= 0x40
+			err = os.WriteFile(p, b, 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = NewGameRepository(dir)
+			if err == nil {
+				t.Fatalf("NewGameRepository() of corrupted log succeeded")
+			}
+			v, err := os.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(v) != string(b) {
+				t.Fatalf("corrupted log has been changed")
+			}
+		})
+	}
+}
+
+func TestGameRepositoryCompact(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	r := open(t, dir)
+	r.compactAfter = 5
+	a, b := repotest.NewGame(time.Now()), repotest.NewGame(time.Now())
+	for _, i := range []*domain.Game{a, b} {
+		err := r.Create(ctx, i, repotest.NewMoves(i, 1))
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	for i := 0; i < 12; i++ {
+		a.Hints++
+		err := r.Update(ctx, a, nil)
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	err := r.Delete(ctx, b.Id)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if r.entries > r.compactAfter+len(r.games) {
+		t.Fatalf("log has %d entries, want at most %d", r.entries, r.compactAfter+len(r.games))
+	}
+	// Compact the log having the deleted game.
+	err = r.compact()
+	if err != nil {
+		t.Fatalf("compact() error = %v", err)
+	}
+	r.Close()
+
+	r = open(t, dir)
+	check(t, r, a, 1)
+	_, err = r.Get(ctx, b.Id)
+	if !errorx.IsNotFound(err) {
+		t.Fatalf("Get() error = %v, want NotFound", err)
+	}
+	// The tombstone is kept by compaction.
+	err = r.Create(ctx, b, nil)
+	if !errorx.IsConflict(err) {
+		t.Fatalf("Create() error = %v, want Conflict", err)
+	}
+}
+
+func open(t *testing.T, dir string) *GameRepository {
+	t.Helper()
+	r, err := NewGameRepository(dir)
+	if err != nil {
+		t.Fatalf("NewGameRepository() error = %v", err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+	})
+	return r
+}
+
+func check(t *testing.T, r *GameRepository, want *domain.Game, moves int) {
+	t.Helper()
+	g, err := r.Get(context.Background(), want.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if g.Board.String() != want.Board.String() || g.Hints != want.Hints || g.Version != want.Version || !g.Created.Equal(want.Created) {
+		t.Fatalf("Get() = %+v, want %+v", g, want)
+	}
+	s, err := r.Moves(context.Background(), want.Id)
+	if err != nil {
+		t.Fatalf("Moves() error = %v", err)
+	}
+	if len(s) != moves {
+		t.Fatalf("Moves() returned %d moves, want %d", len(s), moves)
+	}
+}
@@ -0,0 +1,268 @@
+package file
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+)
+
+// The log is a sequence of entries. Every entry is framed by a header keeping the
+// length and the CRC-32 checksum of its JSON payload, so that a torn write is told
+// from a complete entry.
+const (
+	headerSize = 8
+	// maxEntrySize limits the payload length, so that a corrupted header doesn't make
+	// the loading allocate much memory.
+	maxEntrySize = 16 << 20
+)
+
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opRewind = "rewind"
+	opDelete = "delete"
+	// opBatch applies its entries atomically.
+	opBatch = "batch"
+)
+
+// errCorrupted is returned by readEntry if the entry is incomplete or damaged.
+var errCorrupted = errors.New("corrupted log entry")
+
+// entry is a mutation of a game. Created and updated games are kept in full, the
+// moves are appended on update and truncated to the ply on rewind.
+type entry struct {
+	Op    string       `json:"op"`
+	Id    string       `json:"id"`
+	Game  *gameRecord  `json:"game,omitempty"`
+	Moves []moveRecord `json:"moves,omitempty"`
+	Ply   int          `json:"ply,omitempty"`
+	Time  time.Time    `json:"time"`
+	// Entries are the entries of a batch.
+	Entries []*entry `json:"entries,omitempty"`
+}
+
+type gameRecord struct {
+	Board       string             `json:"board"`
+	Start       string             `json:"start"`
+	Win         int                `json:"win"`
+	Status      string             `json:"status"`
+	Char        string             `json:"char"`
+	FirstMover  string             `json:"firstMover"`
+	LastMover   string             `json:"lastMover"`
+	WinningMove *int               `json:"winningMove,omitempty"`
+	WinningLine *winningLineRecord `json:"winningLine,omitempty"`
+	Winner      string             `json:"winner,omitempty"`
+	Strategy    string             `json:"strategy"`
+	Hints       int                `json:"hints"`
+	UndoLimit   int                `json:"undoLimit"`
+	Undos       int                `json:"undos"`
+	Created     time.Time          `json:"created"`
+	Parent      *parentRecord      `json:"parent,omitempty"`
+	Version     int                `json:"version"`
+}
+
+type winningLineRecord struct {
+	Kind  string `json:"kind"`
+	Cells []int  `json:"cells"`
+}
+
+type parentRecord struct {
+	Id  string `json:"id"`
+	Ply int    `json:"ply"`
+}
+
+type moveRecord struct {
+	Ply    int       `json:"ply"`
+	Player string    `json:"player"`
+	Cell   int       `json:"cell"`
+	Char   string    `json:"char"`
+	Time   time.Time `json:"time"`
+}
+
+func newGameRecord(v *domain.Game) *gameRecord {
+	g := &gameRecord{
+		Board:      v.Board.String(),
+		Start:      v.Start.String(),
+		Win:        v.Board.Win(),
+		Status:     string(v.Status),
+		Char:       string(v.Char),
+		FirstMover: string(v.FirstMover),
+		LastMover:  string(v.LastMover),
+		Winner:     string(v.Winner),
+		Strategy:   string(v.Strategy),
+		Hints:      v.Hints,
+		UndoLimit:  v.UndoLimit,
+		Undos:      v.Undos,
+		Created:    v.Created,
+		Version:    v.Version,
+	}
+	if v.WinningMove != nil {
+		n := *v.WinningMove
+		g.WinningMove = &n
+	}
+	if v.WinningLine != nil {
+		g.WinningLine = &winningLineRecord{
+			Kind:  string(v.WinningLine.Kind),
+			Cells: append([]int(nil), v.WinningLine.Cells...),
+		}
+	}
+	if v.Parent != nil {
+		g.Parent = &parentRecord{Id: string(v.Parent.Id), Ply: v.Parent.Ply}
+	}
+	return g
+}
+
+func (g *gameRecord) to(id string) (*domain.Game, error) {
+	gameId, err := domain.GameIdFromString(id)
+	if err != nil {
+		return nil, err
+	}
+	board, err := domain.GameBoardFromStringWithWin(g.Board, g.Win)
+	if err != nil {
+		return nil, err
+	}
+	start, err := domain.GameBoardFromStringWithWin(g.Start, g.Win)
+	if err != nil {
+		return nil, err
+	}
+	v := &domain.Game{
+		Id:          gameId,
+		Board:       board,
+		Start:       start,
+		Status:      domain.GameStatus(g.Status),
+		Char:        domain.GameBoardChar(g.Char),
+		FirstMover:  domain.GamePlayer(g.FirstMover),
+		LastMover:   domain.GamePlayer(g.LastMover),
+		WinningMove: g.WinningMove,
+		Winner:      domain.GameBoardChar(g.Winner),
+		Strategy:    domain.GameStrategy(g.Strategy),
+		Hints:       g.Hints,
+		UndoLimit:   g.UndoLimit,
+		Undos:       g.Undos,
+		Created:     g.Created,
+		Version:     g.Version,
+	}
+	if g.WinningLine != nil {
+		v.WinningLine = &domain.GameBoardLine{
+			Kind:  domain.GameBoardLineKind(g.WinningLine.Kind),
+			Cells: g.WinningLine.Cells,
+		}
+	}
+	if g.Parent != nil {
+		parentId, err := domain.GameIdFromString(g.Parent.Id)
+		if err != nil {
+			return nil, err
+		}
+		v.Parent = &domain.GameParent{Id: parentId, Ply: g.Parent.Ply}
+	}
+	return v, nil
+}
+
+func newMoveRecords(moves domain.GameMoves) []moveRecord {
+	s := make([]moveRecord, len(moves))
+	for n, i := range moves {
+		s[n] = moveRecord{
+			Ply:    i.Ply,
+			Player: string(i.Player),
+			Cell:   i.Cell,
+			Char:   string(i.Char),
+			Time:   i.Time,
+		}
+	}
+	return s
+}
+
+func (m *moveRecord) to() *domain.GameMove {
+	return &domain.GameMove{
+		Ply:    m.Ply,
+		Player: domain.GamePlayer(m.Player),
+		Cell:   m.Cell,
+		Char:   domain.GameBoardChar(m.Char),
+		Time:   m.Time,
+	}
+}
+
+// appendEntry appends the framed entry to the buffer.
+func appendEntry(buf []byte, e *entry) ([]byte, error) {
+	p, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(p)))
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(p))
+	return append(buf, p...), nil
+}
+
+// readEntry reads the next entry and returns it along with the length of its frame.
+// Returns io.EOF if the log ends before the entry and errCorrupted if the entry is
+// incomplete or damaged.
+func readEntry(r *bufio.Reader) (*entry, int64, error) {
+	var h [headerSize]byte
+	_, err := io.ReadFull(r, h[:])
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	if err == io.ErrUnexpectedEOF {
+		return nil, 0, errCorrupted
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n := binary.BigEndian.Uint32(h[:4])
+	if n > maxEntrySize {
+		return nil, 0, errCorrupted
+	}
+	p := make([]byte, n)
+	_, err = io.ReadFull(r, p)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, 0, errCorrupted
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(p) != binary.BigEndian.Uint32(h[4:]) {
+		return nil, 0, errCorrupted
+	}
+
+	e := &entry{}
+	err = json.Unmarshal(p, e)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", errCorrupted, err)
+	}
+	return e, headerSize + int64(n), nil
+}
+
+// isTail reports whether the damaged entry at the offset is the last write torn by a
+// crash, i.e. no complete entry follows it. The damaged header can't be trusted, so
+// every offset after it is checked for an entry. Zeros the file system might have
+// extended the log with are not taken for an entry.
+func isTail(f *os.File, offset int64) (bool, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	p := make([]byte, st.Size()-offset)
+	_, err = f.ReadAt(p, offset)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	for i := 1; i+headerSize < len(p); i++ {
+		n := binary.BigEndian.Uint32(p[i:])
+		if n == 0 || n > maxEntrySize || int64(n) > int64(len(p)-i-headerSize) {
+			continue
+		}
+		if crc32.ChecksumIEEE(p[i+headerSize:i+headerSize+int(n)]) == binary.BigEndian.Uint32(p[i+4:]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
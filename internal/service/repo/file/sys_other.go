@@ -0,0 +1,15 @@
+//go:build !unix
+
+package file
+
+import "os"
+
+// lock does nothing, the directory must not be shared by several processes.
+func lock(f *os.File) error {
+	return nil
+}
+
+// syncDir does nothing, directories can't be synced.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package file
+
+import (
+	"testing"
+)
+
+func TestGameRepositoryLock(t *testing.T) {
+	dir := t.TempDir()
+	open(t, dir)
+	_, err := NewGameRepository(dir)
+	if err == nil {
+		t.Fatalf("NewGameRepository() of locked directory succeeded")
+	}
+}
@@ -3,6 +3,9 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mgrabazey/tic-tac-toe/internal/domain"
@@ -11,27 +14,156 @@ import (
 )
 
 type game struct {
-	id     string
-	board  string
-	status string
-	char   string
+	id              string
+	board           string
+	startBoard      string
+	status          string
+	char            string
+	size            int
+	win             int
+	firstMover      string
+	lastMover       string
+	winningMove     sql.NullInt16
+	winningLineKind string
+	winningLine     string
+	winner          string
+	strategy        string
+	hints           int
+	undoLimit       int
+	undos           int
+	created         time.Time
+	parentId        sql.NullString
+	parentPly       sql.NullInt32
+	version         int
+}
+
+func newGame(v *domain.Game) *game {
+	g := &game{
+		id:         string(v.Id),
+		board:      v.Board.String(),
+		startBoard: v.Start.String(),
+		status:     string(v.Status),
+		char:       string(v.Char),
+		size:       v.Board.Size(),
+		win:        v.Board.Win(),
+		firstMover: string(v.FirstMover),
+		lastMover:  string(v.LastMover),
+		winner:     string(v.Winner),
+		strategy:   string(v.Strategy),
+		hints:      v.Hints,
+		undoLimit:  v.UndoLimit,
+		undos:      v.Undos,
+		created:    v.Created,
+		version:    v.Version,
+	}
+	if v.Parent != nil {
+		g.parentId = sql.NullString{String: string(v.Parent.Id), Valid: true}
+		g.parentPly = sql.NullInt32{Int32: int32(v.Parent.Ply), Valid: true}
+	}
+	if v.WinningMove != nil {
+		g.winningMove = sql.NullInt16{Int16: int16(*v.WinningMove), Valid: true}
+	}
+	if v.WinningLine != nil {
+		g.winningLineKind = string(v.WinningLine.Kind)
+		s := make([]string, len(v.WinningLine.Cells))
+		for n, i := range v.WinningLine.Cells {
+			s[n] = strconv.Itoa(i)
+		}
+		g.winningLine = strings.Join(s, ",")
+	}
+	return g
 }
 
 func (g *game) scan(scanner func(...any) error) error {
 	return scanner(
 		&g.id,
 		&g.board,
+		&g.startBoard,
 		&g.status,
 		&g.char,
+		&g.size,
+		&g.win,
+		&g.firstMover,
+		&g.lastMover,
+		&g.winningMove,
+		&g.winningLineKind,
+		&g.winningLine,
+		&g.winner,
+		&g.strategy,
+		&g.hints,
+		&g.undoLimit,
+		&g.undos,
+		&g.created,
+		&g.parentId,
+		&g.parentPly,
+		&g.version,
 	)
 }
 
 func (g *game) to() *domain.Game {
-	return &domain.Game{
-		Id:     domain.MustGameIdFromString(g.id),
-		Board:  domain.MustGameBoardFromString(g.board),
-		Status: domain.GameStatus(g.status),
-		Char:   domain.GameBoardChar(g.char),
+	v := &domain.Game{
+		Id:         domain.MustGameIdFromString(g.id),
+		Board:      domain.MustGameBoardFromStringWithWin(g.board, g.win),
+		Start:      domain.MustGameBoardFromStringWithWin(g.startBoard, g.win),
+		Status:     domain.GameStatus(g.status),
+		Char:       domain.GameBoardChar(g.char),
+		FirstMover: domain.GamePlayer(g.firstMover),
+		LastMover:  domain.GamePlayer(g.lastMover),
+		Winner:     domain.GameBoardChar(g.winner),
+		Strategy:   domain.GameStrategy(g.strategy),
+		Hints:      g.hints,
+		UndoLimit:  g.undoLimit,
+		Undos:      g.undos,
+		Created:    g.created,
+		Version:    g.version,
+	}
+	if g.parentId.Valid {
+		v.Parent = &domain.GameParent{
+			Id:  domain.MustGameIdFromString(g.parentId.String),
+			Ply: int(g.parentPly.Int32),
+		}
+	}
+	if g.winningMove.Valid {
+		n := int(g.winningMove.Int16)
+		v.WinningMove = &n
+	}
+	if g.winningLineKind != "" {
+		v.WinningLine = &domain.GameBoardLine{
+			Kind: domain.GameBoardLineKind(g.winningLineKind),
+		}
+		for _, i := range strings.Split(g.winningLine, ",") {
+			n, _ := strconv.Atoi(i)
+			v.WinningLine.Cells = append(v.WinningLine.Cells, n)
+		}
+	}
+	return v
+}
+
+type gameMove struct {
+	ply    int
+	player string
+	cell   int
+	char   string
+	time   time.Time
+}
+
+func (m *gameMove) scan(scanner func(...any) error) error {
+	return scanner(
+		&m.ply,
+		&m.player,
+		&m.cell,
+		&m.char,
+		&m.time,
+	)
+}
+
+func (m *gameMove) to() *domain.GameMove {
+	return &domain.GameMove{
+		Ply:    m.ply,
+		Player: domain.GamePlayer(m.player),
+		Cell:   m.cell,
+		Char:   domain.GameBoardChar(m.char),
+		Time:   m.time,
 	}
 }
 
@@ -46,7 +178,7 @@ func NewGameRepository(db *sql.DB) repo.GameRepository {
 }
 
 func (r *gameRepository) All(ctx context.Context) (domain.Games, error) {
-	q := `SELECT "id", "board", "status", "char" FROM "games" WHERE "deleted_at" IS NULL ORDER BY "created_at" ASC`
+	q := `SELECT "id", "board", "start_board", "status", "char", "size", "win", "first_mover", "last_mover", "winning_move", "winning_line_kind", "winning_line", "winner", "strategy", "hints", "undo_limit", "undos", "created_at", "parent_id", "parent_ply", "version" FROM "games" WHERE "deleted_at" IS NULL ORDER BY "created_at" ASC`
 	v, err := r.db.QueryContext(ctx, q)
 	if err != nil {
 		return nil, err
@@ -66,7 +198,7 @@ func (r *gameRepository) All(ctx context.Context) (domain.Games, error) {
 }
 
 func (r *gameRepository) Get(ctx context.Context, id domain.GameId) (*domain.Game, error) {
-	q := `SELECT "id", "board", "status", "char" FROM "games" WHERE "id" = $1 AND "deleted_at" IS NULL`
+	q := `SELECT "id", "board", "start_board", "status", "char", "size", "win", "first_mover", "last_mover", "winning_move", "winning_line_kind", "winning_line", "winner", "strategy", "hints", "undo_limit", "undos", "created_at", "parent_id", "parent_ply", "version" FROM "games" WHERE "id" = $1 AND "deleted_at" IS NULL`
 	v := r.db.QueryRowContext(ctx, q, id)
 	i := &game{}
 	err := i.scan(v.Scan)
@@ -79,18 +211,109 @@ func (r *gameRepository) Get(ctx context.Context, id domain.GameId) (*domain.Gam
 	return i.to(), nil
 }
 
-func (r *gameRepository) Create(ctx context.Context, game *domain.Game) error {
-	q := `INSERT INTO "games" ("id", "board", "status", "char", "created_at", "updated_at") VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := r.db.ExecContext(ctx, q, game.Id, game.Board.String(), game.Status, game.Char, time.Now(), time.Now())
+func (r *gameRepository) Create(ctx context.Context, game *domain.Game, moves domain.GameMoves) error {
+	err := r.tx(ctx, func(tx *sql.Tx) error {
+		return r.create(ctx, tx, game, moves)
+	})
 	if err != nil {
 		return err
 	}
+	game.Version = 1
 	return nil
 }
 
-func (r *gameRepository) Update(ctx context.Context, game *domain.Game) error {
-	q := `UPDATE "games" SET "board" = $1, "status" = $2, "char" = $3, "updated_at" = $4  WHERE "id" = $5 AND "deleted_at" IS NULL`
-	v, err := r.db.ExecContext(ctx, q, game.Board.String(), game.Status, game.Char, time.Now(), game.Id)
+func (r *gameRepository) CreateAll(ctx context.Context, games domain.Games, moves []domain.GameMoves) error {
+	err := r.tx(ctx, func(tx *sql.Tx) error {
+		for n, i := range games {
+			err := r.create(ctx, tx, i, moves[n])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, i := range games {
+		i.Version = 1
+	}
+	return nil
+}
+
+func (r *gameRepository) Update(ctx context.Context, game *domain.Game, moves domain.GameMoves) error {
+	return r.tx(ctx, func(tx *sql.Tx) error {
+		err := r.update(ctx, tx, game, len(moves) > 0)
+		if err != nil {
+			return err
+		}
+		return r.addMoves(ctx, tx, game.Id, moves)
+	})
+}
+
+func (r *gameRepository) Rewind(ctx context.Context, game *domain.Game, ply int) error {
+	return r.tx(ctx, func(tx *sql.Tx) error {
+		err := r.update(ctx, tx, game, true)
+		if err != nil {
+			return err
+		}
+		q := `DELETE FROM "game_moves" WHERE "game_id" = $1 AND "ply" > $2`
+		_, err = tx.ExecContext(ctx, q, game.Id, ply)
+		return err
+	})
+}
+
+// abandonLock is the key of the advisory lock held while abandoning games.
+const abandonLock = 0x7474745f61626e64
+
+func (r *gameRepository) Abandon(ctx context.Context, before time.Time) (int, error) {
+	var n int64
+	err := r.tx(ctx, func(tx *sql.Tx) error {
+		// Skip if another process is abandoning games, the lock is released on commit.
+		var ok bool
+		err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, abandonLock).Scan(&ok)
+		if err != nil || !ok {
+			return err
+		}
+		q := `UPDATE "games" SET "status" = $1, "updated_at" = $2, "version" = "version" + 1 WHERE "status" = $3 AND "moved_at" < $4 AND "deleted_at" IS NULL`
+		v, err := tx.ExecContext(ctx, q, domain.GameStatusAbandoned, time.Now(), domain.GameStatusRunning, before)
+		if err != nil {
+			return err
+		}
+		n, err = v.RowsAffected()
+		return err
+	})
+	return int(n), err
+}
+
+func (r *gameRepository) Moves(ctx context.Context, id domain.GameId) (domain.GameMoves, error) {
+	_, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	q := `SELECT "ply", "player", "cell", "char", "created_at" FROM "game_moves" WHERE "game_id" = $1 ORDER BY "ply" ASC`
+	v, err := r.db.QueryContext(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer v.Close()
+
+	s := domain.GameMoves{}
+	for v.Next() {
+		i := &gameMove{}
+		err = i.scan(v.Scan)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, i.to())
+	}
+	return s, nil
+}
+
+func (r *gameRepository) Delete(ctx context.Context, id domain.GameId) error {
+	q := `UPDATE "games" SET "deleted_at" = $1 WHERE "id" = $2 AND "deleted_at" IS NULL`
+	v, err := r.db.ExecContext(ctx, q, time.Now(), id)
 	if err != nil {
 		return err
 	}
@@ -104,9 +327,31 @@ func (r *gameRepository) Update(ctx context.Context, game *domain.Game) error {
 	return nil
 }
 
-func (r *gameRepository) Delete(ctx context.Context, id domain.GameId) error {
-	q := `UPDATE "games" SET "deleted_at" = $1 WHERE "id" = $2`
-	v, err := r.db.ExecContext(ctx, q, time.Now(), id)
+// create inserts the game along with the moves. The identifier of a deleted game
+// can't be used again.
+func (r *gameRepository) create(ctx context.Context, tx *sql.Tx, game *domain.Game, moves domain.GameMoves) error {
+	g := newGame(game)
+	q := `INSERT INTO "games" ("id", "board", "start_board", "status", "char", "size", "win", "first_mover", "last_mover", "winning_move", "winning_line_kind", "winning_line", "winner", "strategy", "hints", "undo_limit", "undos", "created_at", "updated_at", "moved_at", "parent_id", "parent_ply", "version") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $19, $20, $21, 1) ON CONFLICT ("id") DO NOTHING`
+	v, err := tx.ExecContext(ctx, q, g.id, g.board, g.startBoard, g.status, g.char, g.size, g.win, g.firstMover, g.lastMover, g.winningMove, g.winningLineKind, g.winningLine, g.winner, g.strategy, g.hints, g.undoLimit, g.undos, g.created, time.Now(), g.parentId, g.parentPly)
+	if err != nil {
+		return err
+	}
+	n, err := v.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errorx.WrapInConflict(fmt.Errorf("game already exists: %s", game.Id))
+	}
+	return r.addMoves(ctx, tx, game.Id, moves)
+}
+
+// update updates the game if its version matches. The time of the last move is
+// updated if moved, so that hints don't keep the game from being abandoned.
+func (r *gameRepository) update(ctx context.Context, tx *sql.Tx, game *domain.Game, moved bool) error {
+	g := newGame(game)
+	q := `UPDATE "games" SET "board" = $1, "status" = $2, "char" = $3, "last_mover" = $4, "winning_move" = $5, "winning_line_kind" = $6, "winning_line" = $7, "winner" = $8, "hints" = $9, "undos" = $10, "updated_at" = $11, "moved_at" = CASE WHEN $14 THEN $11 ELSE "moved_at" END, "version" = "version" + 1  WHERE "id" = $12 AND "version" = $13 AND "deleted_at" IS NULL`
+	v, err := tx.ExecContext(ctx, q, g.board, g.status, g.char, g.lastMover, g.winningMove, g.winningLineKind, g.winningLine, g.winner, g.hints, g.undos, time.Now(), g.id, g.version, moved)
 	if err != nil {
 		return err
 	}
@@ -115,7 +360,46 @@ func (r *gameRepository) Delete(ctx context.Context, id domain.GameId) error {
 		return err
 	}
 	if n == 0 {
+		// Tell the missing game from the one updated concurrently.
+		var ok bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM "games" WHERE "id" = $1 AND "deleted_at" IS NULL)`, g.id).Scan(&ok)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return errorx.WrapInConflict(fmt.Errorf("game has been updated concurrently"))
+		}
 		return errorx.NewNotFound()
 	}
+	game.Version++
 	return nil
 }
+
+func (r *gameRepository) addMoves(ctx context.Context, tx *sql.Tx, id domain.GameId, moves domain.GameMoves) error {
+	q := `INSERT INTO "game_moves" ("game_id", "ply", "player", "cell", "char", "created_at") VALUES ($1, $2, $3, $4, $5, $6)`
+	for _, i := range moves {
+		_, err := tx.ExecContext(ctx, q, id, i.Ply, i.Player, i.Cell, i.Char, i.Time)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tx runs fn within a transaction. The transaction is committed if fn succeeds
+// and rolled back otherwise.
+func (r *gameRepository) tx(ctx context.Context, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	err = fn(tx)
+	return
+}
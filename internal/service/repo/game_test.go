@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo/repotest"
+	"github.com/mgrabazey/tic-tac-toe/internal/pkg/postgres"
+	"github.com/mgrabazey/tic-tac-toe/internal/service/migration"
+)
+
+// TestGameRepository runs against a throwaway database created on the server set by
+// the TEST_DB_* variables and dropped afterwards, the database of TEST_DB_NAME is only
+// connected to create it. The suite abandons every running game of the database, so
+// it must not run against a database in use. It is skipped if TEST_DB_HOST is not set.
+func TestGameRepository(t *testing.T) {
+	host := os.Getenv("TEST_DB_HOST")
+	if host == "" {
+		t.Skip("TEST_DB_HOST is not set")
+	}
+	c := &postgres.Config{
+		User:     os.Getenv("TEST_DB_USER"),
+		Password: os.Getenv("TEST_DB_PASSWORD"),
+		Host:     host,
+		Port:     env("TEST_DB_PORT", "5432"),
+		Name:     env("TEST_DB_NAME", "postgres"),
+	}
+	admin, err := postgres.Conn(c)
+	if err != nil {
+		t.Fatalf("Unable to connect to database: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Close()
+	})
+
+	// Create the throwaway database.
+	c.Name = fmt.Sprintf("tictactoe_test_%d", time.Now().UnixNano())
+	_, err = admin.Exec(`CREATE DATABASE ` + pq.QuoteIdentifier(c.Name))
+	if err != nil {
+		t.Fatalf("Unable to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		_, err := admin.Exec(`DROP DATABASE ` + pq.QuoteIdentifier(c.Name))
+		if err != nil {
+			t.Errorf("Unable to drop database %s: %v", c.Name, err)
+		}
+	})
+	db, err := postgres.Conn(c)
+	if err != nil {
+		t.Fatalf("Unable to connect to database: %v", err)
+	}
+	// Cleanups run in reverse order, the database is closed before it is dropped.
+	t.Cleanup(func() {
+		db.Close()
+	})
+	err = migration.Run(db)
+	if err != nil {
+		t.Fatalf("Unable to run migrations: %v", err)
+	}
+
+	repotest.TestGameRepository(t, func(t *testing.T) repo.GameRepository {
+		return NewGameRepository(db)
+	})
+}
+
+func env(key, fallback string) string {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo"
+)
+
+type idempotencyKey struct {
+	key         string
+	fingerprint string
+	status      sql.NullInt16
+	header      string
+	body        []byte
+	expiresAt   time.Time
+}
+
+func (k *idempotencyKey) scan(scanner func(...any) error) error {
+	return scanner(
+		&k.key,
+		&k.fingerprint,
+		&k.status,
+		&k.header,
+		&k.body,
+		&k.expiresAt,
+	)
+}
+
+func (k *idempotencyKey) to() (*domain.IdempotencyKey, error) {
+	v := &domain.IdempotencyKey{
+		Key:         k.key,
+		Fingerprint: k.fingerprint,
+		ExpiresAt:   k.expiresAt,
+	}
+	if k.status.Valid {
+		v.Response = &domain.IdempotentResponse{
+			Status: int(k.status.Int16),
+			Body:   k.body,
+		}
+		if k.header != "" {
+			err := json.Unmarshal([]byte(k.header), &v.Response.Header)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+type idempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) repo.IdempotencyRepository {
+	return &idempotencyRepository{
+		db: db,
+	}
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, key *domain.IdempotencyKey) (*domain.IdempotencyKey, error) {
+	// Forget expired keys.
+	q := `DELETE FROM "idempotency_keys" WHERE "expires_at" < $1`
+	_, err := r.db.ExecContext(ctx, q, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	q = `INSERT INTO "idempotency_keys" ("key", "fingerprint", "created_at", "expires_at") VALUES ($1, $2, $3, $4) ON CONFLICT ("key") DO NOTHING`
+	v, err := r.db.ExecContext(ctx, q, key.Key, key.Fingerprint, time.Now(), key.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	n, err := v.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return nil, nil
+	}
+
+	q = `SELECT "key", "fingerprint", "status", "header", "body", "expires_at" FROM "idempotency_keys" WHERE "key" = $1`
+	k := &idempotencyKey{}
+	err = k.scan(r.db.QueryRowContext(ctx, q, key.Key).Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// The key has expired just now, try again.
+			return r.Reserve(ctx, key)
+		}
+		return nil, err
+	}
+	return k.to()
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, key string, response *domain.IdempotentResponse) error {
+	h, err := json.Marshal(response.Header)
+	if err != nil {
+		return err
+	}
+	q := `UPDATE "idempotency_keys" SET "status" = $1, "header" = $2, "body" = $3 WHERE "key" = $4`
+	v, err := r.db.ExecContext(ctx, q, response.Status, string(h), response.Body, key)
+	if err != nil {
+		return err
+	}
+	n, err := v.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errorx.NewNotFound()
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key string) error {
+	q := `DELETE FROM "idempotency_keys" WHERE "key" = $1 AND "status" IS NULL`
+	_, err := r.db.ExecContext(ctx, q, key)
+	return err
+}
@@ -0,0 +1,214 @@
+// Package memory implements the repositories keeping entities in memory. Entities are
+// lost once the process exits.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo"
+)
+
+type gameEntry struct {
+	game  *domain.Game
+	moves domain.GameMoves
+	// movedAt is the time of the last move made or taken back, games are abandoned
+	// by it.
+	movedAt time.Time
+	deleted bool
+}
+
+type gameRepository struct {
+	mu    sync.RWMutex
+	games map[domain.GameId]*gameEntry
+	// order is the order the games have been created in.
+	order []domain.GameId
+}
+
+// NewGameRepository creates a new repo.GameRepository safe for concurrent use.
+func NewGameRepository() repo.GameRepository {
+	return &gameRepository{
+		games: make(map[domain.GameId]*gameEntry),
+	}
+}
+
+func (r *gameRepository) All(ctx context.Context) (domain.Games, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var s domain.Games
+	for _, i := range r.order {
+		if g := r.games[i]; !g.deleted {
+			s = append(s, g.game.Clone())
+		}
+	}
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Created.Before(s[j].Created)
+	})
+	return s, nil
+}
+
+func (r *gameRepository) Get(ctx context.Context, id domain.GameId) (*domain.Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	g, err := r.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return g.game.Clone(), nil
+}
+
+func (r *gameRepository) Create(ctx context.Context, game *domain.Game, moves domain.GameMoves) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.games[game.Id]; ok {
+		return errorx.WrapInConflict(fmt.Errorf("game already exists: %s", game.Id))
+	}
+	r.create(game, moves)
+	return nil
+}
+
+func (r *gameRepository) CreateAll(ctx context.Context, games domain.Games, moves []domain.GameMoves) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := make(map[domain.GameId]bool, len(games))
+	for _, i := range games {
+		if _, ok := r.games[i.Id]; ok || m[i.Id] {
+			return errorx.WrapInConflict(fmt.Errorf("game already exists: %s", i.Id))
+		}
+		m[i.Id] = true
+	}
+	for n, i := range games {
+		r.create(i, moves[n])
+	}
+	return nil
+}
+
+func (r *gameRepository) Update(ctx context.Context, game *domain.Game, moves domain.GameMoves) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	g, err := r.update(game)
+	if err != nil {
+		return err
+	}
+	g.moves = append(g.moves, moves.Clone()...)
+	if len(moves) > 0 {
+		g.movedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *gameRepository) Rewind(ctx context.Context, game *domain.Game, ply int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	g, err := r.update(game)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, i := range g.moves {
+		if i.Ply <= ply {
+			g.moves[n] = i
+			n++
+		}
+	}
+	g.moves = g.moves[:n]
+	g.movedAt = time.Now()
+	return nil
+}
+
+func (r *gameRepository) Abandon(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, g := range r.games {
+		if g.deleted || g.game.Status != domain.GameStatusRunning || !g.movedAt.Before(before) {
+			continue
+		}
+		g.game.Status = domain.GameStatusAbandoned
+		g.game.Version++
+		n++
+	}
+	return n, nil
+}
+
+func (r *gameRepository) Moves(ctx context.Context, id domain.GameId) (domain.GameMoves, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	g, err := r.get(id)
+	if err != nil {
+		return nil, err
+	}
+	s := g.moves.Clone()
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Ply < s[j].Ply
+	})
+	return s, nil
+}
+
+func (r *gameRepository) Delete(ctx context.Context, id domain.GameId) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	g, err := r.get(id)
+	if err != nil {
+		return err
+	}
+	g.deleted = true
+	return nil
+}
+
+// create keeps the game of the first version. The write lock must be held.
+func (r *gameRepository) create(game *domain.Game, moves domain.GameMoves) {
+	game.Version = 1
+	r.games[game.Id] = &gameEntry{
+		game:    game.Clone(),
+		moves:   moves.Clone(),
+		movedAt: time.Now(),
+	}
+	r.order = append(r.order, game.Id)
+}
+
+// get returns the game not deleted. The lock must be held.
+func (r *gameRepository) get(id domain.GameId) (*gameEntry, error) {
+	g, ok := r.games[id]
+	if !ok || g.deleted {
+		return nil, errorx.NewNotFound()
+	}
+	return g, nil
+}
+
+// update replaces the game if its version matches and increments the version. The
+// write lock must be held.
+func (r *gameRepository) update(game *domain.Game) (*gameEntry, error) {
+	g, err := r.get(game.Id)
+	if err != nil {
+		return nil, err
+	}
+	if g.game.Version != game.Version {
+		return nil, errorx.WrapInConflict(fmt.Errorf("game has been updated concurrently"))
+	}
+	game.Version++
+	v := game.Clone()
+	// Only the game state can be updated.
+	v.Start = g.game.Start
+	v.FirstMover = g.game.FirstMover
+	v.Strategy = g.game.Strategy
+	v.UndoLimit = g.game.UndoLimit
+	v.Created = g.game.Created
+	v.Parent = g.game.Parent
+	g.game = v
+	return g, nil
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo/repotest"
+)
+
+func TestGameRepository(t *testing.T) {
+	repotest.TestGameRepository(t, func(t *testing.T) repo.GameRepository {
+		return NewGameRepository()
+	})
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mgrabazey/tic-tac-toe/internal/domain"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/error"
+	"github.com/mgrabazey/tic-tac-toe/internal/domain/repo"
+)
+
+type idempotencyRepository struct {
+	mu   sync.Mutex
+	keys map[string]*domain.IdempotencyKey
+}
+
+// NewIdempotencyRepository creates a new repo.IdempotencyRepository safe for concurrent use.
+func NewIdempotencyRepository() repo.IdempotencyRepository {
+	return &idempotencyRepository{
+		keys: make(map[string]*domain.IdempotencyKey),
+	}
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, key *domain.IdempotencyKey) (*domain.IdempotencyKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Forget expired keys.
+	t := time.Now()
+	for n, i := range r.keys {
+		if i.ExpiresAt.Before(t) {
+			delete(r.keys, n)
+		}
+	}
+
+	if v, ok := r.keys[key.Key]; ok {
+		k := *v
+		return &k, nil
+	}
+	k := *key
+	k.Response = nil
+	r.keys[key.Key] = &k
+	return nil, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, key string, response *domain.IdempotentResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.keys[key]
+	if !ok {
+		return errorx.NewNotFound()
+	}
+	m := *response
+	v.Response = &m
+	return nil
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if v, ok := r.keys[key]; ok && v.Response == nil {
+		delete(r.keys, key)
+	}
+	return nil
+}
@@ -18,6 +18,9 @@ $(document).ready(function (){
     const gameStatusCrossWon = "X_WON";
     const gameStatusNoughtWon = "O_WON";
     const gameStatusDraw = "DRAW";
+    const gameStatusResigned = "RESIGNED";
+    const gameStatusDrawAgreed = "DRAW_AGREED";
+    const gameStatusAbandoned = "ABANDONED";
 
     getGames(function (games) {
         games.forEach(function (game) {
@@ -28,7 +31,7 @@ $(document).ready(function (){
         switchPages(pageIds.games);
     });
 
-    $('#games-new').append(drawGameBoard('', blankBoard, gameStatusRunning));
+    $('#games-new').append(drawGameBoard('', blankBoard, gameStatusRunning, ''));
 
     $('#computer').click(function () {
         createGame(blankBoard, function (id) {
@@ -108,7 +111,7 @@ $(document).ready(function (){
     function drawGame(game) {
         let html = $('<div></div>');
         html.append('<div>ID: '+game.id+'</div>');
-        html.append(drawGameBoard(game.id, game.board, game.status));
+        html.append(drawGameBoard(game.id, game.board, game.status, game.playerChar, game.winningLine));
         switch (game.status) {
             case gameStatusDraw:
                 html.append('Game is draw!');
@@ -119,6 +122,15 @@ $(document).ready(function (){
             case gameStatusNoughtWon:
                 html.append('0 won!');
                 break;
+            case gameStatusResigned:
+                html.append('Resigned, '+game.winner+' won!');
+                break;
+            case gameStatusDrawAgreed:
+                html.append('Draw agreed!');
+                break;
+            case gameStatusAbandoned:
+                html.append('Game is abandoned!');
+                break;
             default:
                 html.append('Make a move!');
         }
@@ -126,13 +138,16 @@ $(document).ready(function (){
         return html;
     }
 
-    function drawGameBoard(id, board, status) {
-        let html = '<table data-id="'+id+'" data-board="'+board+'" data-char="X">';
+    function drawGameBoard(id, board, status, char, winningLine) {
+        let winningCells = winningLine ? winningLine.cells : [];
+        let html = '<table data-id="'+id+'" data-board="'+board+'" data-char="'+char+'">';
         let n = 0;
-        for (let i = 0; i < 3; i++) {
+        let size = Math.sqrt(board.length);
+        for (let i = 0; i < size; i++) {
             html += '<tr>';
-            for (let j = 0; j < 3; j++) {
-                html += '<td data-cell="'+n+'">'+board[n]+'</td>'
+            for (let j = 0; j < size; j++) {
+                let cls = winningCells.includes(n) ? ' class="winning"' : '';
+                html += '<td data-cell="'+n+'"'+cls+'>'+board[n]+'</td>'
                 n++
             }
             html += '</tr>';
@@ -168,7 +183,7 @@ $(document).ready(function (){
                     $('#games-list').append(
                         drawGame(game),
                     );
-                    t.replaceWith(drawGameBoard('', blankBoard, gameStatusRunning));
+                    t.replaceWith(drawGameBoard('', blankBoard, gameStatusRunning, ''));
                 });
             });
         }